@@ -0,0 +1,235 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// pipelineBatchSize caps the number of commands sent in a single pipeline
+const pipelineBatchSize = 500
+
+// MultiGetter is implemented by caches that can read many keys in one round trip
+type MultiGetter interface {
+	// GetMulti decodes the value of every key in wanted into the pointer stored for it,
+	// with the same semantics as Get. Keys that could not be read are reported
+	// individually in the returned map (ErrCacheMiss, ErrDecode, ...); the error is
+	// only set when the whole batch failed.
+	GetMulti(ctx context.Context, wanted map[string]interface{}) (map[string]error, error)
+}
+
+// MultiSetter is implemented by caches that can write many keys in one round trip
+type MultiSetter interface {
+	// SetMulti stores every value in items with the same TTL, with the same semantics as Set
+	// Nothing is written when one of the values cannot be encoded
+	SetMulti(ctx context.Context, items map[string]interface{}, ttl time.Duration) error
+}
+
+// GetMulti reads every key in wanted with pipelined GET commands
+// Pipelines work on cluster clients too, where MGET fails for keys in different slots
+func (r *redisCache) GetMulti(ctx context.Context, wanted map[string]interface{}) (map[string]error, error) {
+	failed := make(map[string]error)
+	if len(wanted) == 0 {
+		return failed, nil
+	}
+
+	if err := r.ready(); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(wanted))
+	for key := range wanted {
+		keys = append(keys, key)
+	}
+
+	for start := 0; start < len(keys); start += pipelineBatchSize {
+		batch := keys[start:min(start+pipelineBatchSize, len(keys))]
+
+		cmds := make([]*redis.StringCmd, len(batch))
+		_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range batch {
+				cmds[i] = pipe.Get(ctx, key)
+			}
+			return nil
+		})
+
+		// A pipeline reports the first command error; misses are handled per key below
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, r.backendError(err)
+		}
+
+		for i, key := range batch {
+			data, err := cmds[i].Bytes()
+			if err != nil {
+				failed[key] = backendError(err)
+				continue
+			}
+
+			if err := decodeEntry(data, wanted[key], r.codec); err != nil {
+				failed[key] = err
+			}
+		}
+	}
+
+	return failed, nil
+}
+
+// SetMulti writes every item with pipelined SET commands
+func (r *redisCache) SetMulti(ctx context.Context, items map[string]interface{}, ttl time.Duration) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	encoded := make(map[string][]byte, len(items))
+	for key, value := range items {
+		data, err := r.encode(value)
+		if err != nil {
+			return err
+		}
+		encoded[key] = data
+	}
+
+	if err := r.ready(); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(encoded))
+	for key := range encoded {
+		keys = append(keys, key)
+	}
+
+	for start := 0; start < len(keys); start += pipelineBatchSize {
+		batch := keys[start:min(start+pipelineBatchSize, len(keys))]
+
+		_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range batch {
+				pipe.Set(ctx, key, encoded[key], ttl)
+			}
+			return nil
+		})
+		if err != nil {
+			return r.backendError(err)
+		}
+	}
+
+	return nil
+}
+
+// delKeys deletes keys with pipelined single-key DEL commands and returns how many existed
+// Single-key commands keep deletion working on cluster clients, where a multi-key
+// DEL fails for keys in different slots
+func (r *redisCache) delKeys(ctx context.Context, keys []string) (int64, error) {
+	var deleted int64
+
+	for start := 0; start < len(keys); start += pipelineBatchSize {
+		batch := keys[start:min(start+pipelineBatchSize, len(keys))]
+
+		cmds := make([]*redis.IntCmd, len(batch))
+		_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range batch {
+				cmds[i] = pipe.Del(ctx, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return deleted, r.backendError(err)
+		}
+
+		for _, cmd := range cmds {
+			deleted += cmd.Val()
+		}
+	}
+
+	return deleted, nil
+}
+
+// GetMulti reads sealed entries in one round trip when the inner cache supports it
+// Entries that fail verification are reported individually with ErrIntegrity
+func (s *secureCache) GetMulti(ctx context.Context, wanted map[string]interface{}) (map[string]error, error) {
+	sealed := make(map[string]*[]byte, len(wanted))
+	raw := make(map[string]interface{}, len(wanted))
+	for key := range wanted {
+		sealed[key] = new([]byte)
+		raw[key] = sealed[key]
+	}
+
+	failed, err := getMulti(ctx, s.inner, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, data := range sealed {
+		if _, ok := failed[key]; ok {
+			continue
+		}
+
+		plaintext, reason, err := s.open(key, *data)
+		if err != nil {
+			s.metrics.integrityFailure(reason)
+			failed[key] = fmt.Errorf("%w: %s: %w", ErrIntegrity, reason, err)
+			continue
+		}
+
+		if err := decodeEntry(plaintext, wanted[key], s.codec); err != nil {
+			failed[key] = err
+		}
+	}
+
+	return failed, nil
+}
+
+// SetMulti seals every value and writes them in one round trip when the inner cache supports it
+func (s *secureCache) SetMulti(ctx context.Context, items map[string]interface{}, ttl time.Duration) error {
+	sealed := make(map[string]interface{}, len(items))
+	for key, value := range items {
+		id, payload, err := encodeValue(s.codec, value)
+		if err != nil {
+			return err
+		}
+
+		data, err := s.seal(key, frameEntry(id, payload))
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrEncode, err)
+		}
+		sealed[key] = data
+	}
+
+	return setMulti(ctx, s.inner, sealed, ttl)
+}
+
+// getMulti reads many keys from any Cache, in one round trip when it implements MultiGetter
+func getMulti(ctx context.Context, cache Cache, wanted map[string]interface{}) (map[string]error, error) {
+	if multi, ok := cache.(MultiGetter); ok {
+		return multi.GetMulti(ctx, wanted)
+	}
+
+	failed := make(map[string]error)
+	for key, target := range wanted {
+		if err := cache.Get(ctx, key, target); err != nil {
+			if errors.Is(err, ErrBackendUnavailable) {
+				return nil, err
+			}
+			failed[key] = err
+		}
+	}
+
+	return failed, nil
+}
+
+// setMulti writes many keys to any Cache, in one round trip when it implements MultiSetter
+func setMulti(ctx context.Context, cache Cache, items map[string]interface{}, ttl time.Duration) error {
+	if multi, ok := cache.(MultiSetter); ok {
+		return multi.SetMulti(ctx, items, ttl)
+	}
+
+	for key, value := range items {
+		if err := cache.Set(ctx, key, value, ttl); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
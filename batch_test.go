@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBatch_GetSetMulti tests pipelined batch reads and writes
+func TestBatch_GetSetMulti(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	setter, ok := cache.(MultiSetter)
+	assert.True(t, ok, "redis cache should implement MultiSetter")
+	getter, ok := cache.(MultiGetter)
+	assert.True(t, ok, "redis cache should implement MultiGetter")
+
+	// More items than a single pipeline batch
+	items := make(map[string]interface{})
+	for i := 0; i < pipelineBatchSize+10; i++ {
+		items[fmt.Sprintf("test_batch:%d", i)] = TestObject{ID: fmt.Sprint(i), Name: "user", Age: i}
+	}
+	items["test_batch:raw"] = []byte("raw bytes")
+
+	err = setter.SetMulti(ctx, items, 10*time.Second)
+	assert.NoError(t, err)
+
+	// Read back a mix of existing, missing, raw and mismatched keys
+	var user7, user505, missing TestObject
+	var raw []byte
+	var mismatched int
+	wanted := map[string]interface{}{
+		"test_batch:7":       &user7,
+		"test_batch:505":     &user505,
+		"test_batch:missing": &missing,
+		"test_batch:raw":     &raw,
+		"test_batch:8":       &mismatched,
+	}
+
+	failed, err := getter.GetMulti(ctx, wanted)
+	assert.NoError(t, err)
+	assert.Equal(t, TestObject{ID: "7", Name: "user", Age: 7}, user7)
+	assert.Equal(t, TestObject{ID: "505", Name: "user", Age: 505}, user505)
+	assert.Equal(t, "raw bytes", string(raw))
+
+	assert.Len(t, failed, 2)
+	assert.ErrorIs(t, failed["test_batch:missing"], ErrCacheMiss, "missing key should be reported individually")
+	assert.ErrorIs(t, failed["test_batch:8"], ErrDecode, "mismatched type should be reported individually")
+
+	// Encode failure writes nothing
+	err = setter.SetMulti(ctx, map[string]interface{}{
+		"test_batch:good": "value",
+		"test_batch:bad":  make(chan int),
+	}, 10*time.Second)
+	assert.ErrorIs(t, err, ErrEncode)
+
+	var good string
+	err = cache.Get(ctx, "test_batch:good", &good)
+	assert.ErrorIs(t, err, ErrCacheMiss, "no value should be written when encoding fails")
+
+	// Pipelined delete reports every deleted key
+	deleted, err := cache.(WildcardCounter).DelWildCardCount(ctx, "test_batch:*")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(items)), deleted)
+}
+
+// TestBatch_SecureCache tests batch operations through the secure wrapper
+func TestBatch_SecureCache(t *testing.T) {
+	ctx := context.Background()
+
+	inner, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	secure, err := NewSecureCache(inner, SecureConfig{
+		Keys:        map[string][]byte{"k1": testKeyOld},
+		ActiveKeyID: "k1",
+	})
+	assert.NoError(t, err)
+
+	err = secure.(MultiSetter).SetMulti(ctx, map[string]interface{}{
+		"test_batch_secure:1": "one",
+		"test_batch_secure:2": "two",
+	}, 10*time.Second)
+	assert.NoError(t, err)
+
+	// Unsealed entry next to sealed ones
+	err = inner.Set(ctx, "test_batch_secure:3", "three", 10*time.Second)
+	assert.NoError(t, err)
+
+	var one, two, three, four string
+	failed, err := secure.(MultiGetter).GetMulti(ctx, map[string]interface{}{
+		"test_batch_secure:1": &one,
+		"test_batch_secure:2": &two,
+		"test_batch_secure:3": &three,
+		"test_batch_secure:4": &four,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "one", one)
+	assert.Equal(t, "two", two)
+	assert.ErrorIs(t, failed["test_batch_secure:3"], ErrIntegrity)
+	assert.ErrorIs(t, failed["test_batch_secure:4"], ErrCacheMiss)
+	assert.Len(t, failed, 2)
+
+	err = inner.DelWildCard(ctx, "test_batch_secure:*")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,243 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec serializes values stored through the Cache interface
+// Every entry is stored with the codec ID, so entries written with one codec can
+// still be read after the cache is switched to another one
+type Codec interface {
+	// ID is the format marker stored with each entry
+	// IDs 0-127 are reserved for the built-in codecs
+	ID() byte
+
+	// Marshal encodes v
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal decodes data into v, which must be a pointer
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// Format markers of the built-in codecs
+const (
+	// codecIDRaw marks []byte values, which are stored as-is
+	codecIDRaw byte = 0
+
+	codecIDJSON    byte = 1
+	codecIDMsgPack byte = 2
+	codecIDGob     byte = 3
+	codecIDProto   byte = 4
+)
+
+// entryMagic starts every entry written with a format marker
+// Entries without it were written before codecs existed and are plain JSON or raw bytes
+const entryMagic = "\x00e"
+
+// Built-in codecs
+var (
+	// JSONCodec encodes values with encoding/json (default)
+	JSONCodec Codec = jsonCodec{}
+
+	// MsgPackCodec encodes values with MessagePack; it keeps int64 precision,
+	// time zones and []byte fields intact
+	MsgPackCodec Codec = msgPackCodec{}
+
+	// GobCodec encodes values with encoding/gob; types must be gob-compatible
+	GobCodec Codec = gobCodec{}
+
+	// ProtoCodec encodes proto.Message values with protobuf
+	ProtoCodec Codec = protoCodec{}
+)
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[byte]Codec{
+		codecIDJSON:    JSONCodec,
+		codecIDMsgPack: MsgPackCodec,
+		codecIDGob:     GobCodec,
+		codecIDProto:   ProtoCodec,
+	}
+)
+
+// RegisterCodec makes a custom codec available for decoding stored entries
+// It panics if the ID is reserved or already registered, like database/sql.Register
+func RegisterCodec(codec Codec) {
+	if codec == nil {
+		panic("cache: RegisterCodec codec is nil")
+	}
+
+	if codec.ID() < 128 {
+		panic(fmt.Sprintf("cache: RegisterCodec ID %d is reserved for built-in codecs", codec.ID()))
+	}
+
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	if _, dup := codecs[codec.ID()]; dup {
+		panic(fmt.Sprintf("cache: RegisterCodec called twice for ID %d", codec.ID()))
+	}
+	codecs[codec.ID()] = codec
+}
+
+// lookupCodec returns the codec registered for id
+func lookupCodec(id byte) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	codec, ok := codecs[id]
+	return codec, ok
+}
+
+// encodeValue serializes value with codec and returns the format marker to store with it
+// []byte values are stored as-is so the middleware can serve response bodies directly
+func encodeValue(codec Codec, value interface{}) (byte, []byte, error) {
+	if raw, ok := value.([]byte); ok {
+		return codecIDRaw, raw, nil
+	}
+
+	payload, err := codec.Marshal(value)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: %w", ErrEncode, err)
+	}
+
+	return codec.ID(), payload, nil
+}
+
+// decodeEntry decodes a stored entry into wanted using the codec it was written with
+// A *[]byte receives the payload without decoding, like before codecs existed
+// own is the cache's configured codec, which decodes its entries even when it was never registered
+func decodeEntry(data []byte, wanted interface{}, own Codec) error {
+	id, payload, err := unframeEntry(data)
+	if err != nil {
+		return err
+	}
+
+	if ptr, ok := wanted.(*[]byte); ok {
+		*ptr = payload
+		return nil
+	}
+
+	// Raw and legacy entries were always decoded as JSON
+	if id == codecIDRaw {
+		id = codecIDJSON
+	}
+
+	codec, ok := own, own != nil && own.ID() == id
+	if !ok {
+		codec, ok = lookupCodec(id)
+	}
+	if !ok {
+		return fmt.Errorf("%w: unknown codec %d", ErrDecode, id)
+	}
+
+	if err := codec.Unmarshal(payload, wanted); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecode, err)
+	}
+
+	return nil
+}
+
+// frameEntry prefixes payload with the magic bytes and codec ID
+// Raw payloads stay unmarked, so instances that predate codecs keep serving them as
+// response bodies during a rolling upgrade. Only raw payloads that would be mistaken
+// for a marked entry are framed.
+func frameEntry(id byte, payload []byte) []byte {
+	if id == codecIDRaw && !hasMagic(payload, entryMagic, 1) && !hasMagic(payload, compressedMagic, 2) {
+		return payload
+	}
+
+	data := make([]byte, 0, len(entryMagic)+1+len(payload))
+	data = append(data, entryMagic...)
+	data = append(data, id)
+	return append(data, payload...)
+}
+
+// unframeEntry splits a stored entry into codec ID and payload, decompressing it if needed
+// Entries without magic bytes are legacy entries and are reported as raw
+func unframeEntry(data []byte) (byte, []byte, error) {
+	switch {
+	case hasMagic(data, entryMagic, 1):
+		return data[len(entryMagic)], data[len(entryMagic)+1:], nil
+
+	case hasMagic(data, compressedMagic, 2):
+		algo := Compression(data[len(compressedMagic)])
+		id := data[len(compressedMagic)+1]
+
+		payload, err := decompress(algo, data[len(compressedMagic)+2:])
+		if err != nil {
+			return 0, nil, fmt.Errorf("%w: %w", ErrDecode, err)
+		}
+		return id, payload, nil
+	}
+
+	return codecIDRaw, data, nil
+}
+
+// hasMagic reports whether data starts with magic followed by at least header more bytes
+func hasMagic(data []byte, magic string, header int) bool {
+	return len(data) >= len(magic)+header && string(data[:len(magic)]) == magic
+}
+
+// jsonCodec implements Codec with encoding/json
+type jsonCodec struct{}
+
+func (jsonCodec) ID() byte { return codecIDJSON }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// msgPackCodec implements Codec with MessagePack
+type msgPackCodec struct{}
+
+func (msgPackCodec) ID() byte { return codecIDMsgPack }
+
+func (msgPackCodec) Marshal(v interface{}) ([]byte, error) { return msgpack.Marshal(v) }
+
+func (msgPackCodec) Unmarshal(data []byte, v interface{}) error { return msgpack.Unmarshal(data, v) }
+
+// gobCodec implements Codec with encoding/gob
+type gobCodec struct{}
+
+func (gobCodec) ID() byte { return codecIDGob }
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// protoCodec implements Codec with protobuf
+type protoCodec struct{}
+
+func (protoCodec) ID() byte { return codecIDProto }
+
+func (protoCodec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf codec: %T does not implement proto.Message", v)
+	}
+	return proto.Marshal(msg)
+}
+
+func (protoCodec) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf codec: %T does not implement proto.Message", v)
+	}
+	return proto.Unmarshal(data, msg)
+}
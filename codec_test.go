@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// CodecObject exercises the types JSON handles poorly
+type CodecObject struct {
+	ID      int64
+	Created time.Time
+	Payload []byte
+}
+
+// TestCodecs_RoundTrip tests that every built-in codec preserves values through Redis
+func TestCodecs_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	location, err := time.LoadLocation("Asia/Tashkent")
+	assert.NoError(t, err)
+
+	expected := CodecObject{
+		ID:      1<<62 + 1,
+		Created: time.Date(2024, 5, 1, 12, 30, 0, 0, location),
+		Payload: []byte{0x00, 0xff, 0x10},
+	}
+
+	for name, codec := range map[string]Codec{"msgpack": MsgPackCodec, "gob": GobCodec} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379, Codec: codec})
+			assert.NoError(t, err)
+
+			err = cache.Set(ctx, "test_codec_"+name, expected, 10*time.Second)
+			assert.NoError(t, err)
+
+			var actual CodecObject
+			err = cache.Get(ctx, "test_codec_"+name, &actual)
+			assert.NoError(t, err)
+			assert.Equal(t, expected.ID, actual.ID, "int64 precision should be kept")
+			assert.True(t, expected.Created.Equal(actual.Created), "time should be kept")
+			assert.Equal(t, expected.Payload, actual.Payload, "[]byte field should be kept")
+
+			assert.NoError(t, cache.Del(ctx, "test_codec_"+name))
+		})
+	}
+
+	t.Run("protobuf", func(t *testing.T) {
+		cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379, Codec: ProtoCodec})
+		assert.NoError(t, err)
+
+		err = cache.Set(ctx, "test_codec_proto", wrapperspb.String("hello"), 10*time.Second)
+		assert.NoError(t, err)
+
+		actual := &wrapperspb.StringValue{}
+		err = cache.Get(ctx, "test_codec_proto", actual)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", actual.GetValue())
+
+		err = cache.Set(ctx, "test_codec_proto", "not a message", 10*time.Second)
+		assert.ErrorIs(t, err, ErrEncode, "non-proto values should fail to encode")
+
+		assert.NoError(t, cache.Del(ctx, "test_codec_proto"))
+	})
+}
+
+// TestCodecs_SwitchingCodecs tests that entries stay readable after the codec changes
+func TestCodecs_SwitchingCodecs(t *testing.T) {
+	ctx := context.Background()
+
+	jsonCache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	msgpackCache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379, Codec: MsgPackCodec})
+	assert.NoError(t, err)
+
+	expected := TestObject{ID: "1", Name: "Alice", Age: 25}
+
+	// Written as JSON, read by a msgpack-configured cache
+	err = jsonCache.Set(ctx, "test_codec_switch", expected, 10*time.Second)
+	assert.NoError(t, err)
+
+	var actual TestObject
+	err = msgpackCache.Get(ctx, "test_codec_switch", &actual)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	// Legacy entry written before format markers existed
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+
+	err = client.Set(ctx, "test_codec_legacy", `{"id":"2","name":"Bob","age":35}`, 10*time.Second).Err()
+	assert.NoError(t, err)
+
+	err = msgpackCache.Get(ctx, "test_codec_legacy", &actual)
+	assert.NoError(t, err)
+	assert.Equal(t, TestObject{ID: "2", Name: "Bob", Age: 35}, actual)
+
+	var raw []byte
+	err = msgpackCache.Get(ctx, "test_codec_legacy", &raw)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"2","name":"Bob","age":35}`, string(raw))
+
+	// Unknown format marker
+	err = client.Set(ctx, "test_codec_unknown", "\x00e\xfepayload", 10*time.Second).Err()
+	assert.NoError(t, err)
+
+	err = msgpackCache.Get(ctx, "test_codec_unknown", &actual)
+	assert.ErrorIs(t, err, ErrDecode)
+
+	assert.NoError(t, jsonCache.Del(ctx, "test_codec_switch", "test_codec_legacy", "test_codec_unknown"))
+}
+
+// customCodec is a custom codec used to test registration
+type customCodec struct{}
+
+func (customCodec) ID() byte { return 200 }
+
+func (customCodec) Marshal(v interface{}) ([]byte, error) {
+	return JSONCodec.Marshal(v)
+}
+
+func (customCodec) Unmarshal(data []byte, v interface{}) error {
+	return JSONCodec.Unmarshal(data, v)
+}
+
+// TestCodecs_Register tests custom codec registration rules
+func TestCodecs_Register(t *testing.T) {
+	assert.Panics(t, func() { RegisterCodec(jsonCodec{}) }, "reserved IDs should be rejected")
+
+	RegisterCodec(customCodec{})
+	assert.Panics(t, func() { RegisterCodec(customCodec{}) }, "duplicate IDs should be rejected")
+
+	codec, ok := lookupCodec(200)
+	assert.True(t, ok)
+	assert.Equal(t, customCodec{}, codec)
+}
+
+// unregisteredCodec is a custom codec that is only passed to RedisConfig.Codec
+type unregisteredCodec struct{ customCodec }
+
+func (unregisteredCodec) ID() byte { return 201 }
+
+// TestCodecs_Unregistered tests that a configured codec decodes its own entries without RegisterCodec
+func TestCodecs_Unregistered(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379, Codec: unregisteredCodec{}})
+	assert.NoError(t, err)
+
+	assert.NoError(t, cache.Set(ctx, "test_codec_unregistered", TestObject{ID: "1", Name: "Jane"}, 10*time.Second))
+
+	var got TestObject
+	assert.NoError(t, cache.Get(ctx, "test_codec_unregistered", &got))
+	assert.Equal(t, "Jane", got.Name)
+
+	// Other caches still need the codec registered
+	other, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, other.Get(ctx, "test_codec_unregistered", &got), ErrDecode)
+
+	assert.NoError(t, cache.Del(ctx, "test_codec_unregistered"))
+}
+
+// TestCodecs_RawBytesUnmarked tests that []byte values are stored without a format marker,
+// so instances that predate codecs serve them unchanged
+func TestCodecs_RawBytesUnmarked(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+
+	body := []byte(`{"message":"ok"}`)
+	assert.NoError(t, cache.Set(ctx, "test_codec_raw", body, 10*time.Second))
+	assert.Equal(t, string(body), client.Get(ctx, "test_codec_raw").Val())
+
+	// Bytes that look like a marked entry are framed so they read back unchanged
+	marked := []byte(entryMagic + "\x01{}")
+	assert.NoError(t, cache.Set(ctx, "test_codec_raw", marked, 10*time.Second))
+
+	var got []byte
+	assert.NoError(t, cache.Get(ctx, "test_codec_raw", &got))
+	assert.Equal(t, marked, got)
+
+	assert.NoError(t, cache.Del(ctx, "test_codec_raw"))
+}
@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression selects the algorithm used to compress stored values
+type Compression byte
+
+const (
+	// CompressionNone stores values uncompressed (default)
+	CompressionNone Compression = iota
+
+	// CompressionGzip compresses with gzip; the middleware can send these entries
+	// to clients that accept gzip without decompressing them
+	CompressionGzip
+
+	// CompressionZstd compresses with Zstandard, better ratio and speed than gzip
+	CompressionZstd
+
+	// CompressionSnappy compresses with Snappy, fastest with a lower ratio
+	CompressionSnappy
+)
+
+// defaultCompressionThreshold is the smallest payload worth compressing
+const defaultCompressionThreshold = 1024
+
+// compressedMagic starts every compressed entry
+// It is followed by the Compression byte, the codec ID and the compressed payload
+const compressedMagic = "\x00z"
+
+// String returns the algorithm name as used in Content-Encoding
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionGzip:
+		return "gzip"
+	case CompressionZstd:
+		return "zstd"
+	case CompressionSnappy:
+		return "snappy"
+	default:
+		return fmt.Sprintf("compression(%d)", byte(c))
+	}
+}
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+// zstdCodecs returns the shared zstd encoder and decoder, which are safe for concurrent use
+func zstdCodecs() (*zstd.Encoder, *zstd.Decoder) {
+	zstdOnce.Do(func() {
+		zstdEncoder, _ = zstd.NewWriter(nil)
+		zstdDecoder, _ = zstd.NewReader(nil)
+	})
+	return zstdEncoder, zstdDecoder
+}
+
+// compress compresses data with algo
+func compress(algo Compression, data []byte) ([]byte, error) {
+	switch algo {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+
+	case CompressionZstd:
+		encoder, _ := zstdCodecs()
+		return encoder.EncodeAll(data, nil), nil
+
+	case CompressionSnappy:
+		return snappy.Encode(nil, data), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported compression %s", algo)
+	}
+}
+
+// decompress reverses compress
+func decompress(algo Compression, data []byte) ([]byte, error) {
+	switch algo {
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+
+	case CompressionZstd:
+		_, decoder := zstdCodecs()
+		return decoder.DecodeAll(data, nil)
+
+	case CompressionSnappy:
+		return snappy.Decode(nil, data)
+
+	default:
+		return nil, fmt.Errorf("unsupported compression %s", algo)
+	}
+}
+
+// compressEntry returns the compressed entry for payload, or false when compression
+// is disabled, the payload is below threshold or compressing doesn't make it smaller
+func compressEntry(algo Compression, threshold int, id byte, payload []byte) ([]byte, bool) {
+	if algo == CompressionNone || len(payload) < threshold {
+		return nil, false
+	}
+
+	packed, err := compress(algo, payload)
+	if err != nil || len(packed) >= len(payload) {
+		return nil, false
+	}
+
+	data := make([]byte, 0, len(compressedMagic)+2+len(packed))
+	data = append(data, compressedMagic...)
+	data = append(data, byte(algo), id)
+	return append(data, packed...), true
+}
+
+// gzipEntry returns the gzip stream of a stored raw entry without decompressing it
+// ok is false for entries that are not raw bodies compressed with gzip
+func gzipEntry(data []byte) ([]byte, bool) {
+	if !hasMagic(data, compressedMagic, 2) {
+		return nil, false
+	}
+
+	algo := Compression(data[len(compressedMagic)])
+	id := data[len(compressedMagic)+1]
+	if algo != CompressionGzip || id != codecIDRaw {
+		return nil, false
+	}
+
+	return data[len(compressedMagic)+2:], true
+}
+
+// GzipGetter is implemented by caches that can return a stored []byte value still
+// gzip-compressed. The middleware uses it to send compressed entries to clients that
+// accept gzip without decompressing and recompressing them.
+type GzipGetter interface {
+	// GetGzip returns the stored bytes for key; gzipped reports whether body is a gzip stream
+	GetGzip(ctx context.Context, key string) (body []byte, gzipped bool, err error)
+}
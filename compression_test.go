@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCompression_RoundTrip tests that every algorithm compresses large values and leaves small ones plain
+func TestCompression_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+
+	large := strings.Repeat(`{"id":"1","name":"Alice"},`, 200)
+
+	for _, algo := range []Compression{CompressionGzip, CompressionZstd, CompressionSnappy} {
+		t.Run(algo.String(), func(t *testing.T) {
+			cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379, Compression: algo})
+			assert.NoError(t, err)
+
+			// Large value is compressed
+			err = cache.Set(ctx, "test_compress_large", []byte(large), 10*time.Second)
+			assert.NoError(t, err)
+
+			stored, err := client.Get(ctx, "test_compress_large").Bytes()
+			assert.NoError(t, err)
+			assert.True(t, bytes.HasPrefix(stored, []byte(compressedMagic)), "large value should be compressed")
+			assert.Less(t, len(stored), len(large))
+
+			var body []byte
+			err = cache.Get(ctx, "test_compress_large", &body)
+			assert.NoError(t, err)
+			assert.Equal(t, large, string(body))
+
+			// Small value stays plain
+			err = cache.Set(ctx, "test_compress_small", TestObject{ID: "1", Name: "Alice", Age: 25}, 10*time.Second)
+			assert.NoError(t, err)
+
+			stored, err = client.Get(ctx, "test_compress_small").Bytes()
+			assert.NoError(t, err)
+			assert.True(t, bytes.HasPrefix(stored, []byte(entryMagic)), "small value should not be compressed")
+
+			var object TestObject
+			err = cache.Get(ctx, "test_compress_small", &object)
+			assert.NoError(t, err)
+			assert.Equal(t, "Alice", object.Name)
+
+			assert.NoError(t, cache.Del(ctx, "test_compress_large", "test_compress_small"))
+		})
+	}
+
+	// Plain cache reads compressed entries
+	zstdCache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379, Compression: CompressionZstd})
+	assert.NoError(t, err)
+	plainCache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	err = zstdCache.Set(ctx, "test_compress_mixed", []byte(large), 10*time.Second)
+	assert.NoError(t, err)
+
+	var body []byte
+	err = plainCache.Get(ctx, "test_compress_mixed", &body)
+	assert.NoError(t, err)
+	assert.Equal(t, large, string(body))
+
+	assert.NoError(t, plainCache.Del(ctx, "test_compress_mixed"))
+}
+
+// TestCompression_MiddlewareGzipPassthrough tests that gzip entries are sent as-is to clients that accept gzip
+func TestCompression_MiddlewareGzipPassthrough(t *testing.T) {
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379, Compression: CompressionGzip})
+	assert.NoError(t, err)
+
+	router := setupTestRouter(cache, CacheConfig{TTL: 10 * time.Second})
+
+	items := make([]gin.H, 200)
+	for i := range items {
+		items[i] = gin.H{"id": i, "name": "product"}
+	}
+	router.GET("/v1/product", func(c *gin.Context) {
+		c.JSON(http.StatusOK, items)
+	})
+
+	// Miss: handler response is returned uncompressed
+	w1 := httptest.NewRecorder()
+	router.ServeHTTP(w1, httptest.NewRequest("GET", "/v1/product", nil))
+	assert.Equal(t, http.StatusOK, w1.Code)
+	plain := w1.Body.String()
+
+	// Hit with gzip support: stored gzip stream is sent directly
+	req2 := httptest.NewRequest("GET", "/v1/product", nil)
+	req2.Header.Set("Accept-Encoding", "br, gzip;q=0.8")
+	w2 := httptest.NewRecorder()
+	router.ServeHTTP(w2, req2)
+	assert.Equal(t, "gzip", w2.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w2.Header().Get("Vary"))
+
+	reader, err := gzip.NewReader(w2.Body)
+	assert.NoError(t, err)
+	unzipped, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, string(unzipped))
+
+	// Hit without gzip support: body is decompressed
+	req3 := httptest.NewRequest("GET", "/v1/product", nil)
+	req3.Header.Set("Accept-Encoding", "gzip;q=0")
+	w3 := httptest.NewRecorder()
+	router.ServeHTTP(w3, req3)
+	assert.Empty(t, w3.Header().Get("Content-Encoding"))
+	assert.Equal(t, plain, w3.Body.String())
+
+	// Cleanup
+	err = cache.DelWildCard(context.Background(), "/v1/*")
+	assert.NoError(t, err)
+}
+
+// TestAcceptsGzip tests Accept-Encoding parsing
+func TestAcceptsGzip(t *testing.T) {
+	assert.True(t, acceptsGzip("gzip"))
+	assert.True(t, acceptsGzip("deflate, gzip;q=1.0, *;q=0.5"))
+	assert.True(t, acceptsGzip("*"))
+	assert.True(t, acceptsGzip("*;q=0, gzip"))
+	assert.False(t, acceptsGzip(""))
+	assert.False(t, acceptsGzip("br, deflate"))
+	assert.False(t, acceptsGzip("gzip;q=0"))
+	assert.False(t, acceptsGzip("gzip; q=0.000, *"))
+}
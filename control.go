@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// controlKey is the gin.Context key holding the cache instructions of a handler
+const controlKey = "github.com/xasannosir/gin-redis-cache/control"
+
+// control holds the cache instructions a handler left on the gin.Context
+// SetOrGetCache reads them after c.Next()
+type control struct {
+	// skip keeps the response out of the cache
+	skip bool
+
+	// ttl overrides CacheConfig.TTL when positive
+	ttl time.Duration
+
+	// tags are attached to the stored response
+	tags []string
+
+	// resources are invalidated, with their groups, once the handler returns
+	resources []string
+
+	// invalidateTags are purged once the handler returns
+	invalidateTags []string
+}
+
+// controlOf returns the instructions stored on c, creating them when create is set
+func controlOf(c *gin.Context, create bool) *control {
+	if value, ok := c.Get(controlKey); ok {
+		return value.(*control)
+	}
+
+	if !create {
+		return &control{}
+	}
+
+	ctl := &control{}
+	c.Set(controlKey, ctl)
+	return ctl
+}
+
+// Skip keeps the response of the current request out of the cache,
+// e.g. because it was personalized
+func Skip(c *gin.Context) {
+	controlOf(c, true).skip = true
+}
+
+// SetTTL overrides CacheConfig.TTL for the response of the current request
+func SetTTL(c *gin.Context, ttl time.Duration) {
+	controlOf(c, true).ttl = ttl
+}
+
+// AddTags tags the response of the current request, e.g. with the entities it includes,
+// so it can be purged with InvalidateTagsAfter or Invalidator.InvalidateTags
+// The cache must implement Tagger
+func AddTags(c *gin.Context, tags ...string) {
+	ctl := controlOf(c, true)
+	ctl.tags = append(ctl.tags, tags...)
+}
+
+// InvalidateAfter invalidates resources, with their groups, once the handler returns
+func InvalidateAfter(c *gin.Context, resources ...string) {
+	ctl := controlOf(c, true)
+	ctl.resources = append(ctl.resources, resources...)
+}
+
+// InvalidateTagsAfter purges every response tagged with one of the tags once the handler returns
+func InvalidateTagsAfter(c *gin.Context, tags ...string) {
+	ctl := controlOf(c, true)
+	ctl.invalidateTags = append(ctl.invalidateTags, tags...)
+}
+
+// invalidateAfter runs the invalidations a handler requested with InvalidateAfter and InvalidateTagsAfter
+func (inv *Invalidator) invalidateAfter(c *gin.Context, start time.Time) {
+	ctl := controlOf(c, false)
+
+	var targets []invalidationTarget
+	for _, resource := range ctl.resources {
+		scope := requestScope{resource: resource, collections: []string{collectionPath(resource)}}
+		targets = append(targets, inv.scopeTargets(scope, "setOrGetCache.delWildCard invalidateAfter", "setOrGetCache.delWildCard relatedPath")...)
+	}
+	targets = append(targets, tagTargets("setOrGetCache.invalidateTags", ctl.invalidateTags)...)
+
+	if len(targets) == 0 {
+		return
+	}
+
+	// Failures are already logged and counted by run
+	_, _ = inv.run(c.Request.Context(), c, start, targets)
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestControl_SkipAndTTL tests that handlers can keep responses out of the cache and override the TTL
+func TestControl_SkipAndTTL(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+
+	router := setupTestRouter(cache, CacheConfig{TTL: 10 * time.Second})
+
+	router.GET("/v1/me", func(c *gin.Context) {
+		Skip(c)
+		c.JSON(http.StatusOK, gin.H{"name": "Alice"})
+	})
+	router.GET("/v1/product", func(c *gin.Context) {
+		SetTTL(c, time.Hour)
+		c.JSON(http.StatusOK, gin.H{"message": "product"})
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/me", nil))
+	exists, err := client.Exists(ctx, "/v1/me").Result()
+	assert.NoError(t, err)
+	assert.Zero(t, exists, "skipped response should not be stored")
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/product", nil))
+	ttl, err := client.TTL(ctx, "/v1/product").Result()
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, 10*time.Second, "handler TTL should override the configured TTL")
+
+	// Cleanup
+	assert.NoError(t, cache.DelWildCard(ctx, "/v1/*"))
+}
+
+// TestControl_Tags tests that tagged responses are purged by tag from handlers and the Invalidator
+func TestControl_Tags(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	config := CacheConfig{TTL: 10 * time.Second}
+	router := setupTestRouter(cache, config)
+
+	callCount := map[string]int{}
+	router.GET("/v1/page/:name", func(c *gin.Context) {
+		name := c.Param("name")
+		callCount[name]++
+		switch name {
+		case "home":
+			AddTags(c, "product:1", "category:4")
+		case "deals":
+			AddTags(c, "product:2")
+		}
+		c.JSON(http.StatusOK, gin.H{"page": name})
+	})
+	router.PUT("/v1/stock/:id", func(c *gin.Context) {
+		InvalidateTagsAfter(c, "product:"+c.Param("id"))
+		c.JSON(http.StatusOK, gin.H{"message": "updated"})
+	})
+
+	get := func(name string) {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/page/"+name, nil))
+	}
+
+	get("home")
+	get("deals")
+
+	// A handler purges one tag after it returns
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/v1/stock/1", nil))
+	get("home")
+	get("deals")
+	assert.Equal(t, 2, callCount["home"], "page tagged product:1 should be purged")
+	assert.Equal(t, 1, callCount["deals"], "page tagged product:2 should be kept")
+
+	// The Invalidator purges by tag too
+	report, err := NewInvalidator(cache, config).InvalidateTags(ctx, "category:4", "product:2")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), report.Deleted())
+
+	// Cleanup
+	assert.NoError(t, cache.DelWildCard(ctx, "/v1/*"))
+}
+
+// TestControl_InvalidateAfter tests that handlers can invalidate resources the middleware doesn't know about
+func TestControl_InvalidateAfter(t *testing.T) {
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	router := setupTestRouter(cache, CacheConfig{
+		TTL:      10 * time.Second,
+		Outdoors: []string{"checkout"},
+		Groups: map[string][]string{
+			"inventory": {"warehouse"},
+		},
+	})
+
+	inventoryCallCount := 0
+	warehouseCallCount := 0
+	router.GET("/v1/inventory", func(c *gin.Context) {
+		inventoryCallCount++
+		c.JSON(http.StatusOK, gin.H{"message": "inventory"})
+	})
+	router.GET("/v1/warehouse", func(c *gin.Context) {
+		warehouseCallCount++
+		c.JSON(http.StatusOK, gin.H{"message": "warehouse"})
+	})
+	router.POST("/v1/checkout", func(c *gin.Context) {
+		InvalidateAfter(c, "inventory")
+		c.JSON(http.StatusOK, gin.H{"message": "ordered"})
+	})
+
+	for range 2 {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/inventory", nil))
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/warehouse", nil))
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/checkout", nil))
+	}
+
+	assert.Equal(t, 2, inventoryCallCount, "inventory should be invalidated by the excluded checkout handler")
+	assert.Equal(t, 2, warehouseCallCount, "warehouse should be invalidated through the inventory group")
+
+	// Cleanup
+	assert.NoError(t, cache.DelWildCard(context.Background(), "/v1/*"))
+}
@@ -0,0 +1,30 @@
+package cache
+
+import "errors"
+
+// Sentinel errors returned by Cache implementations.
+// Implementations wrap the underlying cause so callers can branch with errors.Is
+// while still logging the original error.
+var (
+	// ErrCacheMiss is returned by Get when the key does not exist or has expired
+	ErrCacheMiss = errors.New("cache: miss")
+
+	// ErrBackendUnavailable is returned when the cache backend cannot be reached
+	// or fails to execute a command
+	ErrBackendUnavailable = errors.New("cache: backend unavailable")
+
+	// ErrEncode is returned by Set when a value cannot be serialized
+	ErrEncode = errors.New("cache: encode failed")
+
+	// ErrDecode is returned by Get when a stored value cannot be deserialized
+	// into the wanted type
+	ErrDecode = errors.New("cache: decode failed")
+
+	// ErrIntegrity is returned by Get when a protected entry fails authentication,
+	// i.e. it was tampered with, written without protection or sealed with an unknown key
+	ErrIntegrity = errors.New("cache: integrity check failed")
+
+	// ErrCachedLoadFailure is returned by TypedCache.GetOrLoad when a previous loader
+	// failure for the key is still negative-cached
+	ErrCachedLoadFailure = errors.New("cache: cached load failure")
+)
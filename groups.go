@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// expandGroups resolves the resources invalidated with each resource
+// SymmetricGroups adds the reverse of every relationship and TransitiveGroups follows
+// relationships breadth-first; a resource reached twice, e.g. through a cycle, is only
+// listed once and never includes the resource itself
+func expandGroups(config CacheConfig) map[string][]string {
+	edges := make(map[string][]string, len(config.Groups))
+	for resource, related := range config.Groups {
+		edges[resource] = append(edges[resource], related...)
+	}
+
+	if config.SymmetricGroups {
+		// Sorted so the expansion order doesn't depend on map iteration
+		resources := make([]string, 0, len(config.Groups))
+		for resource := range config.Groups {
+			resources = append(resources, resource)
+		}
+		sort.Strings(resources)
+
+		for _, resource := range resources {
+			for _, related := range config.Groups[resource] {
+				if !slices.Contains(edges[related], resource) {
+					edges[related] = append(edges[related], resource)
+				}
+			}
+		}
+	}
+
+	expanded := make(map[string][]string, len(edges))
+	for resource := range edges {
+		visited := map[string]bool{resource: true}
+		queue := slices.Clone(edges[resource])
+		var related []string
+
+		for len(queue) > 0 {
+			next := queue[0]
+			queue = queue[1:]
+
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			related = append(related, next)
+
+			if config.TransitiveGroups {
+				queue = append(queue, edges[next]...)
+			}
+		}
+
+		expanded[resource] = related
+	}
+
+	return expanded
+}
+
+// Validate checks the configuration against the routes registered on a gin engine
+// It reports every group that names a resource no route serves and every nested route
+// that isn't registered, which is usually a typo:
+//
+//	if err := config.Validate(router.Routes()); err != nil {
+//		log.Fatal(err)
+//	}
+func (c CacheConfig) Validate(routes gin.RoutesInfo) error {
+	served := make(map[string]bool, len(routes))
+	registered := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		registered[route.Path] = true
+		served[getBaseURL(route.Path)] = true
+
+		if _, ok := c.NestedRoutes[route.Path]; ok {
+			segments := strings.Split(strings.Trim(route.Path, "/"), "/")
+			if i := lastStaticSegment(segments); i >= 0 {
+				served[segments[i]] = true
+			}
+		}
+	}
+
+	groups := make([]string, 0, len(c.Groups))
+	for group := range c.Groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	var errs []error
+	for _, group := range groups {
+		if !served[group] {
+			errs = append(errs, fmt.Errorf("cache config: group %q is not served by any route", group))
+		}
+		for _, related := range c.Groups[group] {
+			if !served[related] {
+				errs = append(errs, fmt.Errorf("cache config: group %q references resource %q that no route serves", group, related))
+			}
+		}
+	}
+
+	nested := make([]string, 0, len(c.NestedRoutes))
+	for route := range c.NestedRoutes {
+		nested = append(nested, route)
+	}
+	sort.Strings(nested)
+
+	for _, route := range nested {
+		if !registered[route] {
+			errs = append(errs, fmt.Errorf("cache config: nested route %q is not registered", route))
+		}
+	}
+
+	return errors.Join(errs...)
+}
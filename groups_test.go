@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExpandGroups tests one-level, transitive and symmetric group expansion
+func TestExpandGroups(t *testing.T) {
+	groups := map[string][]string{
+		"product":  {"category"},
+		"category": {"menu"},
+		"menu":     {"product"},
+	}
+
+	// One level deep by default
+	expanded := expandGroups(CacheConfig{Groups: groups})
+	assert.Equal(t, []string{"category"}, expanded["product"])
+
+	// Transitive expansion stops at cycles
+	expanded = expandGroups(CacheConfig{Groups: groups, TransitiveGroups: true})
+	assert.Equal(t, []string{"category", "menu"}, expanded["product"])
+	assert.Equal(t, []string{"menu", "product"}, expanded["category"])
+
+	// Symmetric relationships add the reverse direction
+	expanded = expandGroups(CacheConfig{
+		Groups:          map[string][]string{"product": {"category", "brand"}},
+		SymmetricGroups: true,
+	})
+	assert.Equal(t, []string{"category", "brand"}, expanded["product"])
+	assert.Equal(t, []string{"product"}, expanded["category"])
+	assert.Equal(t, []string{"product"}, expanded["brand"])
+
+	// Both together reach siblings through the shared resource
+	expanded = expandGroups(CacheConfig{
+		Groups:           map[string][]string{"product": {"category", "brand"}},
+		SymmetricGroups:  true,
+		TransitiveGroups: true,
+	})
+	assert.Equal(t, []string{"product", "brand"}, expanded["category"])
+}
+
+// TestMiddleware_TransitiveGroups tests that a mutation invalidates resources reached through other groups
+func TestMiddleware_TransitiveGroups(t *testing.T) {
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	router := setupTestRouter(cache, CacheConfig{
+		TTL: 10 * time.Second,
+		Groups: map[string][]string{
+			"product":  {"category"},
+			"category": {"menu"},
+		},
+		TransitiveGroups: true,
+	})
+
+	menuCallCount := 0
+	router.GET("/v1/menu", func(c *gin.Context) {
+		menuCallCount++
+		c.JSON(http.StatusOK, gin.H{"message": "menu"})
+	})
+	router.POST("/v1/product", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"message": "created"})
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/menu", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/product", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/menu", nil))
+
+	assert.Equal(t, 2, menuCallCount, "menu cache should be invalidated through category")
+
+	// Cleanup
+	err = cache.DelWildCard(context.Background(), "/v1/*")
+	assert.NoError(t, err)
+}
+
+// TestCacheConfig_Validate tests that groups naming unserved resources are reported
+func TestCacheConfig_Validate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/v1/product/:id", func(c *gin.Context) {})
+	router.GET("/v1/category", func(c *gin.Context) {})
+
+	config := CacheConfig{
+		Groups: map[string][]string{
+			"product": {"category"},
+		},
+	}
+	assert.NoError(t, config.Validate(router.Routes()))
+
+	config.Groups = map[string][]string{
+		"product": {"categroy"},
+		"brand":   {"product"},
+	}
+	err := config.Validate(router.Routes())
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `group "brand" is not served by any route`)
+		assert.Contains(t, err.Error(), `group "product" references resource "categroy" that no route serves`)
+	}
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// headerWriter wraps gin.ResponseWriter to read the cache headers a handler or upstream
+// service set before they are sent, and to strip them from the client response
+type headerWriter struct {
+	gin.ResponseWriter
+	c                  *gin.Context
+	tagHeader          string
+	invalidationHeader string
+	forward            bool
+	captured           bool
+}
+
+// newHeaderWriter wraps the writer of c when CacheConfig names a cache header
+// It returns nil when there's nothing to capture
+func newHeaderWriter(c *gin.Context, config CacheConfig) *headerWriter {
+	if config.TagHeader == "" && config.InvalidationHeader == "" {
+		return nil
+	}
+
+	w := &headerWriter{
+		ResponseWriter:     c.Writer,
+		c:                  c,
+		tagHeader:          config.TagHeader,
+		invalidationHeader: config.InvalidationHeader,
+		forward:            config.ForwardCacheHeaders,
+	}
+	c.Writer = w
+	return w
+}
+
+// capture turns the cache headers into AddTags and InvalidateTagsAfter instructions
+// It runs once, right before the headers are sent or after the handler returns without writing
+func (w *headerWriter) capture() {
+	if w == nil || w.captured {
+		return
+	}
+	w.captured = true
+
+	header := w.ResponseWriter.Header()
+
+	if w.tagHeader != "" {
+		if tags := parseTags(header.Values(w.tagHeader)); len(tags) > 0 {
+			AddTags(w.c, tags...)
+		}
+		if !w.forward {
+			header.Del(w.tagHeader)
+		}
+	}
+
+	if w.invalidationHeader != "" {
+		if tags := parseTags(header.Values(w.invalidationHeader)); len(tags) > 0 {
+			InvalidateTagsAfter(w.c, tags...)
+		}
+		if !w.forward {
+			header.Del(w.invalidationHeader)
+		}
+	}
+}
+
+// WriteHeaderNow captures the cache headers before sending the headers
+func (w *headerWriter) WriteHeaderNow() {
+	w.capture()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Write captures the cache headers before the first body write sends the headers
+func (w *headerWriter) Write(b []byte) (int, error) {
+	w.capture()
+	return w.ResponseWriter.Write(b)
+}
+
+// WriteString captures the cache headers before the first body write sends the headers
+func (w *headerWriter) WriteString(s string) (int, error) {
+	w.capture()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Flush captures the cache headers before flushing sends the headers
+func (w *headerWriter) Flush() {
+	w.capture()
+	w.ResponseWriter.Flush()
+}
+
+// parseTags splits header values on commas and whitespace, so both the space-separated
+// Surrogate-Key and comma-separated X-Cache-Tags formats work
+func parseTags(values []string) []string {
+	var tags []string
+	for _, value := range values {
+		tags = append(tags, strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})...)
+	}
+	return tags
+}
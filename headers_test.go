@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHeaders_TagAndInvalidate tests that response headers tag stored entries and purge tags
+func TestHeaders_TagAndInvalidate(t *testing.T) {
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	router := setupTestRouter(cache, CacheConfig{
+		TTL:                10 * time.Second,
+		TagHeader:          "Surrogate-Key",
+		InvalidationHeader: "X-Cache-Invalidate",
+	})
+
+	callCount := 0
+	router.GET("/v1/page/home", func(c *gin.Context) {
+		callCount++
+		c.Header("Surrogate-Key", "product:1 category:4")
+		c.JSON(http.StatusOK, gin.H{"page": "home"})
+	})
+	router.DELETE("/v1/stock/:id", func(c *gin.Context) {
+		c.Header("X-Cache-Invalidate", "product:"+c.Param("id"))
+		c.Status(http.StatusNoContent)
+	})
+
+	w1 := httptest.NewRecorder()
+	router.ServeHTTP(w1, httptest.NewRequest("GET", "/v1/page/home", nil))
+	assert.Empty(t, w1.Header().Get("Surrogate-Key"), "tag header should be stripped")
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/page/home", nil))
+	assert.Equal(t, 1, callCount)
+
+	// A mutation response without a body still purges the tag
+	w2 := httptest.NewRecorder()
+	router.ServeHTTP(w2, httptest.NewRequest("DELETE", "/v1/stock/1", nil))
+	assert.Equal(t, http.StatusNoContent, w2.Code)
+	assert.Empty(t, w2.Header().Get("X-Cache-Invalidate"), "invalidation header should be stripped")
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/page/home", nil))
+	assert.Equal(t, 2, callCount, "page tagged product:1 should be purged")
+
+	// Cleanup
+	err = cache.DelWildCard(context.Background(), "/v1/*")
+	assert.NoError(t, err)
+}
+
+// TestHeaders_Forward tests that cache headers reach the client when forwarding is configured
+func TestHeaders_Forward(t *testing.T) {
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	router := setupTestRouter(cache, CacheConfig{
+		TTL:                 10 * time.Second,
+		TagHeader:           "X-Cache-Tags",
+		ForwardCacheHeaders: true,
+	})
+
+	router.GET("/v1/product/:id", func(c *gin.Context) {
+		c.Header("X-Cache-Tags", "product:"+c.Param("id")+",catalog")
+		c.JSON(http.StatusOK, gin.H{"id": c.Param("id")})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/product/1", nil))
+	assert.Equal(t, "product:1,catalog", w.Header().Get("X-Cache-Tags"))
+
+	report, err := NewInvalidator(cache, CacheConfig{}).InvalidateTags(context.Background(), "catalog")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), report.Deleted(), "comma-separated tags should be indexed")
+
+	// Cleanup
+	err = cache.DelWildCard(context.Background(), "/v1/*")
+	assert.NoError(t, err)
+}
+
+// TestParseTags tests tag header parsing
+func TestParseTags(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c", "d"}, parseTags([]string{"a b", " c,d ,"}))
+	assert.Empty(t, parseTags([]string{"", " , "}))
+}
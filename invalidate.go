@@ -0,0 +1,419 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InvalidationResult describes a single pattern or key deleted by an Invalidator
+type InvalidationResult struct {
+	// Pattern is the wildcard pattern, the exact key for InvalidateKey or the tag for InvalidateTags
+	Pattern string
+
+	// Resource is the invalidated resource
+	Resource string
+
+	// Group is the resource whose change triggered the invalidation
+	Group string
+
+	// Deleted is the number of keys removed, 0 when the cache can't count them
+	Deleted int64
+
+	// Err is the failure, if any
+	Err error
+}
+
+// InvalidationReport lists everything deleted by one invalidation call
+type InvalidationReport struct {
+	Results []InvalidationResult
+}
+
+// Deleted returns the total number of keys removed
+func (r InvalidationReport) Deleted() int64 {
+	var deleted int64
+	for _, result := range r.Results {
+		deleted += result.Deleted
+	}
+	return deleted
+}
+
+// GroupScope selects what an item mutation invalidates in a related resource
+type GroupScope int
+
+const (
+	// GroupScopeResource invalidates every key of the related resource (default)
+	GroupScopeResource GroupScope = iota
+
+	// GroupScopeCollection invalidates only the collection and list keys of the related resource
+	GroupScopeCollection
+
+	// GroupScopeNone leaves the related resource alone on item mutations
+	GroupScopeNone
+)
+
+// defaultItemParams are the gin params that identify an item route
+var defaultItemParams = []string{"id"}
+
+// invalidationTarget is a single pattern or exact key deleted by an Invalidator
+type invalidationTarget struct {
+	// op names the operation for error events
+	op string
+
+	pattern string
+
+	// exact deletes pattern as a key instead of matching it
+	exact bool
+
+	// keys are every variant of an exact key, deleted together; pattern is the first
+	keys []string
+
+	// tag deletes the keys tagged with pattern
+	tag bool
+
+	resource string
+	group    string
+}
+
+// Invalidator deletes cached responses outside HTTP mutations, e.g. from background jobs
+// It expands Groups exactly like the middleware and reports to the same logger, metrics and tracer
+type Invalidator struct {
+	cache Cache
+
+	// groups maps a resource to every resource invalidated with it
+	groups map[string][]string
+
+	entityInvalidation bool
+	itemParams         []string
+	groupScopes        map[string]GroupScope
+	nestedRoutes       map[string]NestedRoute
+
+	// namespace prefixes every key and tag, see keyNamespace
+	namespace string
+
+	// rawNamespace and version are CacheConfig.Namespace and CacheConfig.Version
+	rawNamespace     string
+	version          string
+	versionRetention time.Duration
+	heartbeat        *versionHeartbeat
+
+	// partition extracts the request partition, nil when keys aren't partitioned
+	partition func(c *gin.Context) string
+
+	// perUser is set when some reads are cached per user
+	perUser bool
+
+	// prefixIndex is the key of the partition and user key prefixes in use, see PrefixIndexer
+	prefixIndex string
+
+	obs *observer
+}
+
+// NewInvalidator creates an Invalidator from the middleware configuration
+func NewInvalidator(cache Cache, config CacheConfig) *Invalidator {
+	inv := &Invalidator{
+		cache:              cache,
+		groups:             expandGroups(config),
+		entityInvalidation: config.EntityInvalidation,
+		itemParams:         config.ItemParams,
+		groupScopes:        config.GroupScopes,
+		nestedRoutes:       config.NestedRoutes,
+		namespace:          keyNamespace(config.Namespace, config.Version),
+		rawNamespace:       config.Namespace,
+		version:            config.Version,
+		versionRetention:   config.VersionRetention,
+		partition:          config.Partition,
+		perUser:            len(config.PerUserKeys) > 0,
+		prefixIndex:        prefixIndexKeyPrefix + keyNamespace(config.Namespace, config.Version),
+		obs:                newObserver(config),
+	}
+
+	if len(inv.itemParams) == 0 {
+		inv.itemParams = defaultItemParams
+	}
+
+	if inv.versionRetention <= 0 {
+		inv.versionRetention = defaultVersionRetention
+	}
+	inv.heartbeat = newVersionHeartbeat(cache, config, inv.versionRetention)
+
+	return inv
+}
+
+// InvalidateResource deletes every cached response of resource and of the resources in its group
+// The report is always returned; the error joins the failures of individual patterns
+func (inv *Invalidator) InvalidateResource(ctx context.Context, resource string) (InvalidationReport, error) {
+	scope := requestScope{resource: resource, collections: []string{collectionPath(resource)}}
+	return inv.run(ctx, nil, time.Now(), inv.scopeTargets(scope, "invalidator.delWildCard resource", "invalidator.delWildCard related"))
+}
+
+// InvalidatePath deletes every cached response of the resource path belongs to and of its group,
+// e.g. "/v1/product/42" invalidates product. Paths matching a NestedRoutes pattern resolve like
+// the middleware, so "/v1/shop/7/product/3" invalidates the scoped and top-level product caches.
+// Use InvalidateEntity to clear a single item.
+func (inv *Invalidator) InvalidatePath(ctx context.Context, path string) (InvalidationReport, error) {
+	scope := inv.resolvePath(path)
+	return inv.run(ctx, nil, time.Now(), inv.scopeTargets(scope, "invalidator.delWildCard resource", "invalidator.delWildCard related"))
+}
+
+// InvalidateEntity deletes the cached responses of a single item, e.g. ("product", "42"),
+// together with the resource's collection and list keys
+// Resources in its group are handled according to GroupScopes
+func (inv *Invalidator) InvalidateEntity(ctx context.Context, resource, id string) (InvalidationReport, error) {
+	scope := requestScope{resource: resource, collections: []string{collectionPath(resource)}, id: id}
+	return inv.run(ctx, nil, time.Now(), inv.scopeTargets(scope, "invalidator.delWildCard entity", "invalidator.delWildCard related"))
+}
+
+// InvalidateKey deletes exact cache keys, e.g. "/v1/product?page=2", without group expansion
+func (inv *Invalidator) InvalidateKey(ctx context.Context, keys ...string) (InvalidationReport, error) {
+	targets := make([]invalidationTarget, 0, len(keys))
+	for _, key := range keys {
+		path, _, _ := strings.Cut(key, "?")
+		resource := getBaseURL(path)
+		targets = append(targets, invalidationTarget{op: "invalidator.del key", pattern: key, exact: true, resource: resource})
+	}
+
+	return inv.run(ctx, nil, time.Now(), targets)
+}
+
+// invalidateRequest invalidates what the mutation resolved to scope affects
+func (inv *Invalidator) invalidateRequest(c *gin.Context, start time.Time, scope requestScope) {
+	targets := inv.scopeTargets(scope, "setOrGetCache.delWildCard baseUrl", "setOrGetCache.delWildCard relatedPath")
+
+	// Failures are already logged and counted by run
+	_, _ = inv.run(c.Request.Context(), c, start, targets)
+}
+
+// scopeTargets invalidates every collection of scope followed by the related resources
+// Without an item id a collection loses every key below it. With one, only the item, its
+// query variants and sub-resources, and the collection and its list variants are deleted.
+func (inv *Invalidator) scopeTargets(scope requestScope, op, relatedOp string) []invalidationTarget {
+	resource := scope.resource
+	var targets []invalidationTarget
+
+	for _, collection := range scope.collections {
+		if scope.id == "" {
+			targets = append(targets, invalidationTarget{op: op, pattern: escapeGlob(collection) + "*", resource: resource, group: resource})
+			continue
+		}
+
+		item := collection + "/" + scope.id
+		targets = append(targets,
+			invalidationTarget{op: op, pattern: item, exact: true, resource: resource, group: resource},
+			invalidationTarget{op: op, pattern: escapeGlob(item) + `\?*`, resource: resource, group: resource},
+			invalidationTarget{op: op, pattern: escapeGlob(item) + "/*", resource: resource, group: resource},
+			invalidationTarget{op: op, pattern: collection, exact: true, resource: resource, group: resource},
+			invalidationTarget{op: op, pattern: escapeGlob(collection) + `\?*`, resource: resource, group: resource},
+		)
+	}
+
+	for _, related := range inv.groups[resource] {
+		// GroupScopes only narrow item mutations
+		groupScope := GroupScopeResource
+		if scope.id != "" {
+			groupScope = inv.groupScopes[related]
+		}
+
+		switch groupScope {
+		case GroupScopeResource:
+			targets = append(targets, invalidationTarget{op: relatedOp, pattern: resourcePattern(related), resource: related, group: resource})
+
+		case GroupScopeCollection:
+			relatedCollection := collectionPath(related)
+			targets = append(targets,
+				invalidationTarget{op: relatedOp, pattern: relatedCollection, exact: true, resource: related, group: resource},
+				invalidationTarget{op: relatedOp, pattern: escapeGlob(relatedCollection) + `\?*`, resource: related, group: resource},
+			)
+		}
+	}
+
+	return targets
+}
+
+// run repeats the targets for every key variant of the partition and deletes them
+// c is nil outside the middleware
+func (inv *Invalidator) run(ctx context.Context, c *gin.Context, start time.Time, targets []invalidationTarget) (InvalidationReport, error) {
+	return inv.invalidateTargets(ctx, c, start, inv.withPrefixes(ctx, targets, inv.keyPrefixes(inv.partitionOf(ctx, c))))
+}
+
+// invalidateTargets deletes every target inside its own span and records the results
+// Every target is attempted even when an earlier one fails
+func (inv *Invalidator) invalidateTargets(ctx context.Context, c *gin.Context, start time.Time, targets []invalidationTarget) (InvalidationReport, error) {
+	report := InvalidationReport{Results: make([]InvalidationResult, 0, len(targets))}
+
+	fanout := 0
+	for _, target := range targets {
+		if target.resource != target.group {
+			fanout++
+		}
+	}
+
+	for _, target := range targets {
+		report.Results = append(report.Results, inv.invalidate(ctx, c, start, target, fanout))
+	}
+
+	return report, report.err()
+}
+
+// withPrefixes repeats every pattern target for each key prefix
+// Exact keys become one target deleting the key under every indexed prefix
+// Tag targets are kept once, tag sets index the full keys
+// The namespace comes first and is matched literally
+func (inv *Invalidator) withPrefixes(ctx context.Context, targets []invalidationTarget, prefixes []string) []invalidationTarget {
+	if inv.namespace == "" && len(prefixes) == 1 && prefixes[0] == "" {
+		return targets
+	}
+
+	// Only read the index when an exact key needs it
+	var concrete []string
+	indexed := false
+	if slices.ContainsFunc(targets, func(target invalidationTarget) bool { return target.exact }) {
+		concrete, indexed = inv.concretePrefixes(ctx, prefixes)
+	}
+
+	all := make([]invalidationTarget, 0, len(targets)*len(prefixes))
+	for _, target := range targets {
+		if target.tag {
+			target.pattern = inv.namespace + target.pattern
+			all = append(all, target)
+			continue
+		}
+
+		if target.exact && indexed {
+			keyed := target
+			keyed.keys = make([]string, len(concrete))
+			for i, prefix := range concrete {
+				keyed.keys[i] = inv.namespace + prefix + target.pattern
+			}
+			keyed.pattern = keyed.keys[0]
+			all = append(all, keyed)
+			continue
+		}
+
+		for _, prefix := range prefixes {
+			prefixed := target
+
+			switch {
+			case !target.exact:
+				prefixed.pattern = escapeGlob(inv.namespace) + prefix + target.pattern
+
+			// Without an index, a wildcard prefix turns an exact key into a pattern
+			case strings.Contains(prefix, "*"):
+				prefixed.pattern = escapeGlob(inv.namespace) + prefix + escapeGlob(target.pattern)
+				prefixed.exact = false
+
+			default:
+				prefixed.pattern = inv.namespace + prefix + target.pattern
+			}
+
+			all = append(all, prefixed)
+		}
+	}
+	return all
+}
+
+// concretePrefixes replaces the wildcard prefixes with the indexed prefixes they match
+// It reports false when the cache has no usable index, e.g. it doesn't implement PrefixIndexer
+func (inv *Invalidator) concretePrefixes(ctx context.Context, prefixes []string) ([]string, bool) {
+	var concrete, wildcards []string
+	for _, prefix := range prefixes {
+		if stem, _, ok := strings.Cut(prefix, "*"); ok {
+			wildcards = append(wildcards, stem)
+		} else {
+			concrete = append(concrete, prefix)
+		}
+	}
+	if len(wildcards) == 0 {
+		return concrete, true
+	}
+
+	indexed, err := indexedPrefixes(ctx, inv.cache, inv.prefixIndex)
+	if err != nil {
+		return nil, false
+	}
+	slices.Sort(indexed)
+
+	for _, prefix := range indexed {
+		for _, stem := range wildcards {
+			if strings.HasPrefix(prefix, stem) && !slices.Contains(concrete, prefix) {
+				concrete = append(concrete, prefix)
+				break
+			}
+		}
+	}
+	return concrete, true
+}
+
+// invalidate deletes a single target inside a span and records the result
+// fanout is the number of related-resource targets in the same invalidation
+func (inv *Invalidator) invalidate(ctx context.Context, c *gin.Context, start time.Time, target invalidationTarget, fanout int) InvalidationResult {
+	result := InvalidationResult{Pattern: target.pattern, Resource: target.resource, Group: target.group}
+
+	// Exact keys carry query strings, so spans only see their hash like lookups and stores
+	key := attrPattern.String(target.pattern)
+	if target.exact {
+		key = attrKeyHash.String(hashKey(target.pattern))
+	}
+
+	ctx, span := startSpan(ctx, inv.obs.tracer, spanInvalidate,
+		key,
+		attrResource.String(target.resource),
+		attrGroup.String(target.group),
+		attrGroupFanout.Int(fanout),
+	)
+
+	switch {
+	case target.tag:
+		result.Deleted, result.Err = invalidateTags(ctx, inv.cache, target.pattern)
+	case target.exact && len(target.keys) > 0:
+		result.Deleted, result.Err = delCount(ctx, inv.cache, target.keys...)
+	case target.exact:
+		result.Deleted, result.Err = delCount(ctx, inv.cache, target.pattern)
+	default:
+		result.Deleted, result.Err = delWildCard(ctx, inv.cache, target.pattern)
+	}
+
+	if result.Err != nil {
+		endSpan(span, OutcomeError, result.Err)
+		inv.obs.record(ctx, c, start, cacheEvent{outcome: OutcomeError, op: target.op, key: result.Pattern, resource: result.Resource, group: result.Group, err: result.Err})
+		return result
+	}
+
+	span.SetAttributes(attrKeysDeleted.Int64(result.Deleted))
+	endSpan(span, OutcomeInvalidate, nil)
+	inv.obs.record(ctx, c, start, cacheEvent{outcome: OutcomeInvalidate, key: result.Pattern, resource: result.Resource, group: result.Group, deleted: result.Deleted})
+	return result
+}
+
+// err joins the failures of individual results
+func (r InvalidationReport) err() error {
+	var errs []error
+	for _, result := range r.Results {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// collectionPath returns the top-level collection path of resource
+func collectionPath(resource string) string {
+	return "/v1/" + resource
+}
+
+// resourcePattern returns the wildcard pattern matching every cached response of resource
+func resourcePattern(resource string) string {
+	return escapeGlob(collectionPath(resource)) + "*"
+}
+
+// escapeGlob escapes the characters Redis KEYS treats as wildcards
+func escapeGlob(s string) string {
+	return globEscaper.Replace(s)
+}
+
+// globEscaper backslash-escapes Redis glob metacharacters
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
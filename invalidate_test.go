@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestInvalidator_Resource tests resource invalidation with group expansion and the report
+func TestInvalidator_Resource(t *testing.T) {
+	ctx := context.Background()
+	metrics := NewMetrics(MetricsConfig{})
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	invalidator := NewInvalidator(cache, CacheConfig{
+		Groups: map[string][]string{
+			"product": {"category", "brand"},
+		},
+		Metrics: metrics,
+	})
+
+	for _, key := range []string{"/v1/product/1", "/v1/product?page=2", "/v1/category/3", "/v1/user/4"} {
+		assert.NoError(t, cache.Set(ctx, key, []byte(`{}`), 10*time.Second))
+	}
+
+	report, err := invalidator.InvalidateResource(ctx, "product")
+	assert.NoError(t, err)
+	assert.Equal(t, []InvalidationResult{
+		{Pattern: "/v1/product*", Resource: "product", Group: "product", Deleted: 2},
+		{Pattern: "/v1/category*", Resource: "category", Group: "product", Deleted: 1},
+		{Pattern: "/v1/brand*", Resource: "brand", Group: "product", Deleted: 0},
+	}, report.Results)
+	assert.Equal(t, int64(3), report.Deleted())
+
+	// Unrelated resources are kept
+	var body []byte
+	assert.NoError(t, cache.Get(ctx, "/v1/user/4", &body))
+
+	// Programmatic invalidations are counted like middleware ones
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.invalidations.WithLabelValues("category", "product")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.invalidatedKeys.WithLabelValues("product", "product")))
+
+	// InvalidatePath resolves the resource from the path
+	report, err = invalidator.InvalidatePath(ctx, "/v1/user/4")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), report.Deleted())
+}
+
+// TestInvalidator_Key tests exact key invalidation
+func TestInvalidator_Key(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	invalidator := NewInvalidator(cache, CacheConfig{
+		Groups: map[string][]string{
+			"product": {"category"},
+		},
+	})
+
+	for _, key := range []string{"/v1/product?page=1", "/v1/product?page=2", "/v1/category/1"} {
+		assert.NoError(t, cache.Set(ctx, key, []byte(`{}`), 10*time.Second))
+	}
+
+	report, err := invalidator.InvalidateKey(ctx, "/v1/product?page=2", "/v1/product?page=3")
+	assert.NoError(t, err)
+	assert.Equal(t, []InvalidationResult{
+		{Pattern: "/v1/product?page=2", Resource: "product", Deleted: 1},
+		{Pattern: "/v1/product?page=3", Resource: "product", Deleted: 0},
+	}, report.Results)
+
+	// Neither other keys of the resource nor its group are touched
+	var body []byte
+	assert.NoError(t, cache.Get(ctx, "/v1/product?page=1", &body))
+	assert.NoError(t, cache.Get(ctx, "/v1/category/1", &body))
+
+	assert.NoError(t, cache.DelWildCard(ctx, "/v1/*"))
+}
+
+// TestInvalidator_Errors tests that failures are reported per pattern and joined
+func TestInvalidator_Errors(t *testing.T) {
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+	assert.NoError(t, cache.(Closer).Close())
+
+	invalidator := NewInvalidator(cache, CacheConfig{
+		Groups: map[string][]string{
+			"product": {"category"},
+		},
+	})
+
+	report, err := invalidator.InvalidateResource(context.Background(), "product")
+	assert.ErrorIs(t, err, ErrBackendUnavailable)
+	if assert.Len(t, report.Results, 2, "every pattern should be attempted") {
+		assert.ErrorIs(t, report.Results[0].Err, ErrBackendUnavailable)
+		assert.ErrorIs(t, report.Results[1].Err, ErrBackendUnavailable)
+	}
+}
+
+// TestMiddleware_EntityInvalidation tests that item mutations only clear the item, collection and list keys
+func TestMiddleware_EntityInvalidation(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	router := setupTestRouter(cache, CacheConfig{
+		TTL: 10 * time.Second,
+		Groups: map[string][]string{
+			"product": {"category", "brand", "review"},
+		},
+		EntityInvalidation: true,
+		GroupScopes: map[string]GroupScope{
+			"category": GroupScopeCollection,
+			"brand":    GroupScopeNone,
+		},
+	})
+
+	router.PUT("/v1/product/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "updated"})
+	})
+	router.POST("/v1/product", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"message": "created"})
+	})
+
+	seed := func() {
+		for _, key := range []string{
+			"/v1/product/123", "/v1/product/123?fields=name", "/v1/product/123/reviews",
+			"/v1/product/1234", "/v1/product/456", "/v1/product", "/v1/product?page=2",
+			"/v1/category", "/v1/category?page=2", "/v1/category/1",
+			"/v1/brand", "/v1/review/1",
+		} {
+			assert.NoError(t, cache.Set(ctx, key, []byte(`{}`), 10*time.Second))
+		}
+	}
+	cached := func(key string) bool {
+		var body []byte
+		return cache.Get(ctx, key, &body) == nil
+	}
+
+	seed()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/v1/product/123", nil))
+
+	for _, key := range []string{"/v1/product/123", "/v1/product/123?fields=name", "/v1/product/123/reviews", "/v1/product", "/v1/product?page=2"} {
+		assert.False(t, cached(key), "%s should be invalidated", key)
+	}
+	for _, key := range []string{"/v1/product/1234", "/v1/product/456"} {
+		assert.True(t, cached(key), "%s belongs to another item", key)
+	}
+
+	// Related resources follow their scope
+	assert.False(t, cached("/v1/category"))
+	assert.False(t, cached("/v1/category?page=2"))
+	assert.True(t, cached("/v1/category/1"), "collection scope should keep category items")
+	assert.True(t, cached("/v1/brand"), "none scope should keep brand")
+	assert.False(t, cached("/v1/review/1"), "resource scope should clear review")
+
+	// Mutations of collection routes still clear the whole resource
+	seed()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/product", nil))
+	assert.False(t, cached("/v1/product/456"))
+	assert.False(t, cached("/v1/brand"))
+
+	// Cleanup
+	assert.NoError(t, cache.DelWildCard(ctx, "/v1/*"))
+}
+
+// TestInvalidator_Entity tests programmatic entity invalidation with glob characters in the id
+func TestInvalidator_Entity(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	invalidator := NewInvalidator(cache, CacheConfig{})
+
+	for _, key := range []string{"/v1/product/a*", "/v1/product/a*?x=1", "/v1/product/abc", "/v1/product"} {
+		assert.NoError(t, cache.Set(ctx, key, []byte(`{}`), 10*time.Second))
+	}
+
+	report, err := invalidator.InvalidateEntity(ctx, "product", "a*")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), report.Deleted())
+
+	var body []byte
+	assert.NoError(t, cache.Get(ctx, "/v1/product/abc", &body), "glob characters in the id should be matched literally")
+
+	assert.NoError(t, cache.DelWildCard(ctx, "/v1/*"))
+}
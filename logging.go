@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Outcome describes what the middleware did with a request
+type Outcome string
+
+const (
+	// OutcomeHit means the response was served from the cache
+	OutcomeHit Outcome = "hit"
+
+	// OutcomeMiss means the response was not cached and the handler was called
+	OutcomeMiss Outcome = "miss"
+
+	// OutcomeStore means the handler response was written to the cache
+	OutcomeStore Outcome = "store"
+
+	// OutcomeBypass means the request was not eligible for caching
+	OutcomeBypass Outcome = "bypass"
+
+	// OutcomeInvalidate means cached entries were deleted after a mutation
+	OutcomeInvalidate Outcome = "invalidate"
+
+	// OutcomeError means a cache operation failed
+	OutcomeError Outcome = "error"
+)
+
+// defaultLogLevels keeps high-volume events out of production logs unless asked for
+var defaultLogLevels = map[Outcome]slog.Level{
+	OutcomeHit:        slog.LevelDebug,
+	OutcomeMiss:       slog.LevelDebug,
+	OutcomeStore:      slog.LevelDebug,
+	OutcomeBypass:     slog.LevelDebug,
+	OutcomeInvalidate: slog.LevelInfo,
+	OutcomeError:      slog.LevelError,
+}
+
+// defaultRequestIDHeader is the header read for the request_id attribute
+const defaultRequestIDHeader = "X-Request-ID"
+
+// cacheEvent describes a single cache event emitted by the middleware
+type cacheEvent struct {
+	// outcome is the kind of event
+	outcome Outcome
+
+	// op names the failing operation for error events, e.g. "setOrGetCache.get cacheKey"
+	op string
+
+	// key is the cache key or invalidation pattern the event refers to
+	key string
+
+	// resource is the resource type resolved from the request path
+	resource string
+
+	// group is the mutated resource that triggered an invalidation
+	group string
+
+	// deleted is the number of keys removed by an invalidation
+	deleted int64
+
+	// size is the stored body size for store events
+	size int
+
+	// err is the failure for error events
+	err error
+}
+
+// eventLogger emits cache events to a structured logger or the legacy Logger func
+type eventLogger struct {
+	slog            *slog.Logger
+	legacy          func(message string, args ...interface{})
+	levels          map[Outcome]slog.Level
+	sampleRate      uint64
+	sampleCounter   atomic.Uint64
+	requestIDHeader string
+}
+
+// newEventLogger builds an eventLogger from the middleware configuration
+func newEventLogger(config CacheConfig) *eventLogger {
+	l := &eventLogger{
+		slog:            config.Slog,
+		legacy:          config.Logger,
+		levels:          make(map[Outcome]slog.Level, len(defaultLogLevels)),
+		sampleRate:      config.LogSampleRate,
+		requestIDHeader: config.RequestIDHeader,
+	}
+
+	if l.legacy == nil {
+		l.legacy = noopLogger
+	}
+
+	if l.requestIDHeader == "" {
+		l.requestIDHeader = defaultRequestIDHeader
+	}
+
+	for outcome, level := range defaultLogLevels {
+		l.levels[outcome] = level
+	}
+	for outcome, level := range config.LogLevels {
+		l.levels[outcome] = level
+	}
+
+	return l
+}
+
+// log emits the event, c is nil for events outside a request
+// Without a structured logger only error events reach the legacy Logger func
+func (l *eventLogger) log(ctx context.Context, c *gin.Context, start time.Time, e cacheEvent) {
+	if l.slog == nil {
+		if e.outcome == OutcomeError {
+			l.legacy(e.op, e.err)
+		}
+		return
+	}
+
+	level := l.levels[e.outcome]
+
+	if !l.slog.Enabled(ctx, level) || !l.sample(e.outcome) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("outcome", string(e.outcome)),
+		slog.String("key", e.key),
+		slog.String("resource", e.resource),
+		slog.Duration("duration", time.Since(start)),
+	}
+
+	if c != nil {
+		attrs = append(attrs,
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+		)
+
+		if requestID := c.GetHeader(l.requestIDHeader); requestID != "" {
+			attrs = append(attrs, slog.String("request_id", requestID))
+		}
+	}
+
+	if e.group != "" {
+		attrs = append(attrs, slog.String("group", e.group))
+	}
+
+	if e.op != "" {
+		attrs = append(attrs, slog.String("op", e.op))
+	}
+
+	if e.err != nil {
+		attrs = append(attrs, slog.Any("error", e.err))
+	}
+
+	l.slog.LogAttrs(ctx, level, "cache "+string(e.outcome), attrs...)
+}
+
+// sample reports whether a hit or miss event should be logged
+// Every other outcome is always logged
+func (l *eventLogger) sample(outcome Outcome) bool {
+	if outcome != OutcomeHit && outcome != OutcomeMiss {
+		return true
+	}
+
+	if l.sampleRate <= 1 {
+		return true
+	}
+
+	return l.sampleCounter.Add(1)%l.sampleRate == 1
+}
@@ -0,0 +1,212 @@
+package cache
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultMetricsNamespace prefixes every metric name unless MetricsConfig.Namespace is set
+const defaultMetricsNamespace = "gin_redis_cache"
+
+// MetricsConfig defines the naming and bucket layout of the cache metrics
+type MetricsConfig struct {
+	// Namespace prefixes every metric name (default "gin_redis_cache")
+	Namespace string
+
+	// Subsystem is an optional second metric name prefix
+	Subsystem string
+
+	// LatencyBuckets are the histogram buckets for Redis operation latency in seconds
+	LatencyBuckets []float64
+
+	// SizeBuckets are the histogram buckets for stored response body size in bytes
+	SizeBuckets []float64
+
+	// ConstLabels are added to every metric, e.g. the service name
+	ConstLabels prometheus.Labels
+}
+
+// Metrics collects Prometheus metrics for the cache middleware and Redis operations
+// It implements prometheus.Collector and is ready to be registered with a registry
+type Metrics struct {
+	requests        *prometheus.CounterVec
+	invalidations   *prometheus.CounterVec
+	invalidatedKeys *prometheus.CounterVec
+	redisDuration   *prometheus.HistogramVec
+	bodySize        *prometheus.HistogramVec
+	integrity       *prometheus.CounterVec
+}
+
+// NewMetrics creates a new metrics collector
+// Register it with prometheus.MustRegister and pass it to CacheConfig.Metrics and RedisConfig.Metrics
+func NewMetrics(cfg MetricsConfig) *Metrics {
+	if cfg.Namespace == "" {
+		cfg.Namespace = defaultMetricsNamespace
+	}
+
+	if cfg.LatencyBuckets == nil {
+		cfg.LatencyBuckets = prometheus.ExponentialBuckets(0.0005, 2, 14)
+	}
+
+	if cfg.SizeBuckets == nil {
+		cfg.SizeBuckets = prometheus.ExponentialBuckets(256, 4, 10)
+	}
+
+	return &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   cfg.Namespace,
+			Subsystem:   cfg.Subsystem,
+			Name:        "requests_total",
+			Help:        "Cache middleware events by outcome (hit, miss, bypass, store, error), route and resource.",
+			ConstLabels: cfg.ConstLabels,
+		}, []string{"outcome", "route", "resource"}),
+
+		invalidations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   cfg.Namespace,
+			Subsystem:   cfg.Subsystem,
+			Name:        "invalidations_total",
+			Help:        "Invalidation patterns executed by invalidated resource and the group (mutated resource) that triggered them.",
+			ConstLabels: cfg.ConstLabels,
+		}, []string{"resource", "group"}),
+
+		invalidatedKeys: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   cfg.Namespace,
+			Subsystem:   cfg.Subsystem,
+			Name:        "invalidated_keys_total",
+			Help:        "Cache keys deleted by invalidation by invalidated resource and group.",
+			ConstLabels: cfg.ConstLabels,
+		}, []string{"resource", "group"}),
+
+		redisDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   cfg.Namespace,
+			Subsystem:   cfg.Subsystem,
+			Name:        "redis_operation_duration_seconds",
+			Help:        "Latency of Redis commands issued by the cache, by command and status.",
+			Buckets:     cfg.LatencyBuckets,
+			ConstLabels: cfg.ConstLabels,
+		}, []string{"operation", "status"}),
+
+		bodySize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   cfg.Namespace,
+			Subsystem:   cfg.Subsystem,
+			Name:        "stored_body_bytes",
+			Help:        "Size of response bodies written to the cache, by resource.",
+			Buckets:     cfg.SizeBuckets,
+			ConstLabels: cfg.ConstLabels,
+		}, []string{"resource"}),
+
+		integrity: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   cfg.Namespace,
+			Subsystem:   cfg.Subsystem,
+			Name:        "integrity_failures_total",
+			Help:        "Protected entries rejected on Get because they were tampered with, unprotected or sealed with an unknown key.",
+			ConstLabels: cfg.ConstLabels,
+		}, []string{"reason"}),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.invalidations.Describe(ch)
+	m.invalidatedKeys.Describe(ch)
+	m.redisDuration.Describe(ch)
+	m.bodySize.Describe(ch)
+	m.integrity.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.requests.Collect(ch)
+	m.invalidations.Collect(ch)
+	m.invalidatedKeys.Collect(ch)
+	m.redisDuration.Collect(ch)
+	m.bodySize.Collect(ch)
+	m.integrity.Collect(ch)
+}
+
+// observe records a cache event, c is nil for events outside a request
+// It is safe to call on a nil *Metrics so metrics stay optional
+func (m *Metrics) observe(c *gin.Context, e cacheEvent) {
+	if m == nil {
+		return
+	}
+
+	if e.outcome == OutcomeInvalidate {
+		m.invalidations.WithLabelValues(e.resource, e.group).Inc()
+		m.invalidatedKeys.WithLabelValues(e.resource, e.group).Add(float64(e.deleted))
+		return
+	}
+
+	route := ""
+	if c != nil {
+		route = c.FullPath()
+	}
+
+	m.requests.WithLabelValues(string(e.outcome), route, e.resource).Inc()
+
+	if e.outcome == OutcomeStore {
+		m.bodySize.WithLabelValues(e.resource).Observe(float64(e.size))
+	}
+}
+
+// integrityFailure records an entry rejected by a secure cache
+func (m *Metrics) integrityFailure(reason string) {
+	if m == nil {
+		return
+	}
+	m.integrity.WithLabelValues(reason).Inc()
+}
+
+// RedisHook returns a go-redis hook that records command latency
+// NewRedisCache installs it automatically when RedisConfig.Metrics is set;
+// use it directly to instrument a client created elsewhere
+func (m *Metrics) RedisHook() redis.Hook {
+	return metricsHook{metrics: m}
+}
+
+// metricsHook is a go-redis hook that observes command latency
+type metricsHook struct {
+	metrics *Metrics
+}
+
+// DialHook passes dialing through unchanged
+func (h metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+// ProcessHook times a single command
+func (h metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.metrics.redisDuration.WithLabelValues(cmd.Name(), commandStatus(err)).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// ProcessPipelineHook times a pipeline as a single operation
+func (h metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		h.metrics.redisDuration.WithLabelValues("pipeline", commandStatus(err)).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// commandStatus classifies a command result for the status label
+// A missing key is a normal reply, not a failure
+func commandStatus(err error) string {
+	if err == nil || err == redis.Nil {
+		return "ok"
+	}
+	return "error"
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMetrics_MiddlewareCounters tests that hits, misses, stores and invalidations are counted
+func TestMetrics_MiddlewareCounters(t *testing.T) {
+	metrics := NewMetrics(MetricsConfig{})
+
+	registry := prometheus.NewRegistry()
+	err := registry.Register(metrics)
+	assert.NoError(t, err, "metrics should register as a collector")
+
+	cfg := RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Password: "",
+		Database: 0,
+		Metrics:  metrics,
+	}
+	cache, err := NewRedisCache(cfg)
+	assert.NoError(t, err)
+
+	config := CacheConfig{
+		TTL: 10 * time.Second,
+		Groups: map[string][]string{
+			"product": {"category"},
+		},
+		Outdoors: []string{"health"},
+		Metrics:  metrics,
+	}
+
+	router := setupTestRouter(cache, config)
+
+	router.GET("/v1/product/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "product"})
+	})
+	router.GET("/v1/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+	router.POST("/v1/product", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"message": "created"})
+	})
+
+	// Miss + store, then hit
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/product/1", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/product/1", nil))
+
+	// Bypass for excluded resource
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/health", nil))
+
+	// Invalidation of product and its group
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/product", nil))
+
+	route := "/v1/product/:id"
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.requests.WithLabelValues("miss", route, "product")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.requests.WithLabelValues("store", route, "product")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.requests.WithLabelValues("hit", route, "product")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.requests.WithLabelValues("bypass", "/v1/health", "health")))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.invalidations.WithLabelValues("product", "product")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.invalidations.WithLabelValues("category", "product")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.invalidatedKeys.WithLabelValues("product", "product")), "one product key should be deleted")
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.invalidatedKeys.WithLabelValues("category", "product")))
+
+	// Body size and Redis latency histograms should have observations
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.bodySize))
+	assert.Positive(t, testutil.CollectAndCount(metrics.redisDuration), "redis commands should be timed")
+
+	// Cleanup
+	err = cache.DelWildCard(context.Background(), "/v1/*")
+	assert.NoError(t, err)
+}
+
+// TestMetrics_LookupErrorCountedOnce tests that a failed lookup is counted as an error and not also as a miss
+func TestMetrics_LookupErrorCountedOnce(t *testing.T) {
+	ctx := context.Background()
+	metrics := NewMetrics(MetricsConfig{})
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	// A compressed entry that can't be decompressed fails with ErrDecode
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+	assert.NoError(t, client.Set(ctx, "/v1/broken", compressedMagic+"\x01\x00not gzip", 10*time.Second).Err())
+
+	router := setupTestRouter(cache, CacheConfig{TTL: 10 * time.Second, Metrics: metrics})
+	router.GET("/v1/broken", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "broken"})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/broken", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.requests.WithLabelValues("error", "/v1/broken", "broken")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.requests.WithLabelValues("miss", "/v1/broken", "broken")))
+
+	assert.NoError(t, cache.Del(ctx, "/v1/broken"))
+}
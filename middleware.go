@@ -2,13 +2,17 @@ package cache
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
-	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // noopLogger is a silent logger that does nothing
@@ -26,11 +30,121 @@ type CacheConfig struct {
 	// When a resource is modified, all related resources in its group are invalidated
 	Groups map[string][]string
 
+	// TransitiveGroups follows Groups recursively, so with product -> category and
+	// category -> menu a product change invalidates menu too. Cycles are safe.
+	TransitiveGroups bool
+
+	// SymmetricGroups makes every Groups relationship work in both directions
+	SymmetricGroups bool
+
+	// EntityInvalidation limits mutations of item routes such as PUT /v1/product/:id to the
+	// item's keys plus the collection and list keys of the resource, instead of every key of it
+	EntityInvalidation bool
+
+	// ItemParams are the gin params that identify an item route (default "id")
+	ItemParams []string
+
+	// GroupScopes selects per related resource what an item mutation invalidates in it
+	// Resources without an entry are invalidated completely
+	GroupScopes map[string]GroupScope
+
+	// PerUserKeys lists rules for reads that are cached per user
+	// GET requests carrying an Authorization header or session cookies bypass the cache unless
+	// they match one of these rules, in which case the key is partitioned by a credentials hash
+	PerUserKeys []Rule
+
+	// SessionCookies names the cookies that identify a user (default: every cookie)
+	SessionCookies []string
+
+	// Sensitive marks further GET requests that must never be cached, e.g. by a query parameter
+	Sensitive func(c *gin.Context) bool
+
+	// Namespace prefixes every key, tag and invalidation pattern, e.g. with the service name,
+	// so services sharing a Redis database never read or invalidate each other's entries
+	Namespace string
+
+	// Version is added to the namespace, e.g. a build or release ID, so responses are
+	// never served across incompatible releases
+	Version string
+
+	// VersionRetention is how long entries of a Version without a heartbeat are kept before
+	// Invalidator.CleanupStaleNamespaces deletes them (default 1h)
+	// The active version is recorded in Redis on the first request and about every minute after.
+	VersionRetention time.Duration
+
+	// Partition extracts a partition such as a tenant or user ID from the request, e.g. with
+	// PartitionByHeader. Responses are cached per partition, and mutations only invalidate
+	// their own partition. Requests with an empty partition are unpartitioned and their
+	// mutations invalidate every partition.
+	// Credentialed requests still bypass the cache unless they match PerUserKeys.
+	Partition func(c *gin.Context) string
+
+	// TagHeader is a response header listing tags for the stored response, e.g. "Surrogate-Key"
+	// or "X-Cache-Tags". Tags are separated by spaces or commas and work like AddTags.
+	TagHeader string
+
+	// InvalidationHeader is a response header listing tags to purge once the handler returns,
+	// e.g. "X-Cache-Invalidate". It works like InvalidateTagsAfter.
+	InvalidationHeader string
+
+	// ForwardCacheHeaders sends TagHeader and InvalidationHeader to the client, e.g. for a CDN
+	// By default they are stripped from the response
+	ForwardCacheHeaders bool
+
+	// NestedRoutes lists nested route patterns, e.g. "/v1/shop/:shop_id/product/:id", whose
+	// resource is their last static segment rather than the first one
+	NestedRoutes map[string]NestedRoute
+
+	// PreflightTTL caches CORS preflight responses, i.e. OPTIONS requests with Origin and
+	// Access-Control-Request-Method headers, per origin and requested method and headers
+	// Preflights are not cached when it is zero
+	PreflightTTL time.Duration
+
+	// CacheableReads marks routes with a request body, e.g. POST searches or GraphQL queries,
+	// as reads. They are cached like GET requests under a hash of the canonical JSON body and
+	// never invalidate. GET, HEAD and OPTIONS requests never match.
+	CacheableReads []Rule
+
+	// MaxReadBodySize caps the body of CacheableReads (default 64 KiB)
+	// Larger requests bypass the cache
+	MaxReadBodySize int64
+
 	// Outdoors (ExcludedPaths) lists API endpoints that should not be cached
+	// Deprecated: use Exclude. Each entry is an Exclude rule for the resource.
 	Outdoors []string
 
+	// Include limits caching and invalidation to requests matching at least one rule
+	// Without Include rules every request is included
+	Include []Rule
+
+	// Exclude bypasses caching and invalidation for requests matching any rule
+	// Handlers of excluded requests can still use InvalidateAfter
+	Exclude []Rule
+
 	// Logger is an optional custom logger function
+	// Deprecated: use Slog. Logger is ignored when Slog is set.
 	Logger func(message string, args ...interface{})
+
+	// Slog is an optional structured logger for cache events
+	// Each event carries key, method, route, resource, duration, outcome and request_id attributes
+	Slog *slog.Logger
+
+	// LogLevels overrides the slog level used for each outcome
+	// Hit, miss, store and bypass default to Debug, invalidate to Info and error to Error
+	LogLevels map[Outcome]slog.Level
+
+	// LogSampleRate logs only one of every N hit and miss events; 0 or 1 logs all of them
+	LogSampleRate uint64
+
+	// RequestIDHeader is the request header used for the request_id attribute (default "X-Request-ID")
+	RequestIDHeader string
+
+	// Metrics is an optional Prometheus collector for hit/miss/bypass/store/error and invalidation counts
+	Metrics *Metrics
+
+	// TracerProvider enables OpenTelemetry spans for cache lookups, stores and invalidations
+	// Spans are children of the span found in c.Request.Context()
+	TracerProvider trace.TracerProvider
 }
 
 // responseWriter wraps gin.ResponseWriter to capture response body for caching
@@ -45,6 +159,70 @@ func (w *responseWriter) Write(b []byte) (int, error) {
 	return w.ResponseWriter.Write(b)
 }
 
+// observer fans cache events out to the configured logger, metrics and tracer
+type observer struct {
+	logger  *eventLogger
+	metrics *Metrics
+	tracer  trace.Tracer
+}
+
+// newObserver builds an observer from the middleware configuration
+func newObserver(config CacheConfig) *observer {
+	return &observer{
+		logger:  newEventLogger(config),
+		metrics: config.Metrics,
+		tracer:  newTracer(config.TracerProvider),
+	}
+}
+
+// event records a cache event for the current request
+func (o *observer) event(c *gin.Context, start time.Time, e cacheEvent) {
+	o.record(c.Request.Context(), c, start, e)
+}
+
+// record records a cache event, c is nil for events outside a request
+func (o *observer) record(ctx context.Context, c *gin.Context, start time.Time, e cacheEvent) {
+	o.logger.log(ctx, c, start, e)
+	o.metrics.observe(c, e)
+}
+
+// available reports whether the cache can serve requests
+// Caches that don't implement Availability are assumed to be always available
+func available(cache Cache) bool {
+	if a, ok := cache.(Availability); ok {
+		return a.Available()
+	}
+	return true
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows a gzip response
+// An explicit gzip entry wins over the "*" wildcard, and q=0 refuses the coding
+func acceptsGzip(header string) bool {
+	wildcard := false
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+
+		switch coding {
+		case "gzip":
+			return !refusedCoding(params)
+		case "*":
+			wildcard = !refusedCoding(params)
+		}
+	}
+	return wildcard
+}
+
+// refusedCoding reports whether Accept-Encoding parameters carry q=0
+func refusedCoding(params string) bool {
+	q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q=")
+	if !ok {
+		return false
+	}
+	value, err := strconv.ParseFloat(q, 64)
+	return err == nil && value == 0
+}
+
 // getBaseURL extracts the resource type from the URL path
 // For example, "/v1/product/123" returns "product"
 func getBaseURL(path string) string {
@@ -82,64 +260,151 @@ func getCacheKey(c *gin.Context) string {
 
 // SetOrGetCache returns a Gin middleware that handles HTTP caching
 // GET requests: serve from cache if available, otherwise cache the response
+// HEAD requests: answer from the GET entry if available, on routes that handle HEAD
+// OPTIONS preflight requests: cached when PreflightTTL is set
+// CacheableReads: served and cached like GET, keyed by the request body
 // POST/PUT/PATCH/DELETE requests: invalidate related caches
 func SetOrGetCache(cache Cache, config CacheConfig) gin.HandlerFunc {
-	if config.Logger == nil {
-		config.Logger = noopLogger
-	}
+	invalidator := NewInvalidator(cache, config)
+	obs := invalidator.obs
+	rules := newRuleSet(config)
+	privacy := newPrivacy(config)
+	reads := newBodyReads(config)
 
 	return func(c *gin.Context) {
+		start := time.Now()
 		method := c.Request.Method
 		path := c.Request.URL.Path
 
-		baseURL := getBaseURL(path)
+		invalidator.heartbeat.beat()
+
+		scope := invalidator.resolve(c)
+		baseURL := scope.resource
 
-		// Skip caching for excluded endpoints
-		if slices.Contains(config.Outdoors, baseURL) {
+		// Cache headers are read once the handler returns, even if it never wrote a body
+		headers := newHeaderWriter(c, config)
+		next := func() {
 			c.Next()
+			headers.capture()
+		}
+
+		// Skip caching for excluded endpoints and while the backend is degraded
+		if !rules.handles(c, baseURL) || !available(cache) {
+			obs.event(c, start, cacheEvent{outcome: OutcomeBypass, key: path, resource: baseURL})
+			next()
+
+			// Excluded handlers may still ask for invalidations
+			if available(cache) {
+				invalidator.invalidateAfter(c, start)
+			}
 			return
 		}
 
+		// Reads with a body, such as POST searches, are cached and never invalidate
+		read := reads.matches(c, baseURL)
+
 		// Handle cache invalidation for mutating operations
-		if method == "POST" || method == "PUT" || method == "PATCH" || method == "DELETE" {
+		if !read && (method == "POST" || method == "PUT" || method == "PATCH" || method == "DELETE") {
+			// Invalidate caches for this resource type, or only the item on item routes,
+			// and for related resource types
+			invalidator.invalidateRequest(c, start, scope)
 
-			// Invalidate all caches for this resource type
-			err := cache.DelWildCard(c.Request.Context(), "/v1/"+baseURL+"*")
-			if err != nil {
-				config.Logger("setOrGetCache.delWildCard baseUrl", err)
-			}
+			next()
 
-			// Invalidate caches for related resource types
-			if relatedPaths, ok := config.Groups[baseURL]; ok {
-				for _, relatedPath := range relatedPaths {
-					err = cache.DelWildCard(c.Request.Context(), "/v1/"+relatedPath+"*")
-					if err != nil {
-						config.Logger("setOrGetCache.delWildCard relatedPath", err)
-					}
-				}
-			}
+			invalidator.invalidateAfter(c, start)
+			return
+		}
 
-			c.Next()
+		// CORS preflights are cached per origin and requested method and headers
+		if config.PreflightTTL > 0 && isPreflight(c) {
+			servePreflight(c, cache, obs, invalidator.namespace+preflightKey(c), config.PreflightTTL, start, baseURL, next)
+
+			invalidator.invalidateAfter(c, start)
 			return
 		}
 
 		// Handle cache retrieval and storage for GET requests
-		if method == "GET" {
-			cacheKey := getCacheKey(c)
+		// HEAD requests read the GET entry but never store one, their handlers write no body
+		// Without a HEAD route gin answers 404, so they are passed through to keep the status
+		// independent of the cache
+		if method == "GET" || (method == "HEAD" && c.FullPath() != "") || read {
+			// Oversized bodies bypass the cache
+			cacheKey, cacheable := getCacheKey(c), true
+			if read {
+				cacheKey, cacheable = reads.key(c, cacheKey)
+			}
+
+			// Authenticated and sensitive responses must not reach other users
+			// A partition never replaces this: it may come from a header any client can send
+			var keyPrefix string
+			if cacheable {
+				keyPrefix, cacheable = privacy.keyPrefix(c, baseURL)
+			}
+			if cacheable && config.Partition != nil {
+				keyPrefix = partitionPrefix(config.Partition(c)) + keyPrefix
+			}
+			cacheKey = invalidator.namespace + keyPrefix + cacheKey
+			if !cacheable {
+				obs.event(c, start, cacheEvent{outcome: OutcomeBypass, key: path, resource: baseURL})
+				next()
+
+				invalidator.invalidateAfter(c, start)
+				return
+			}
+
+			keyHash := attrKeyHash.String(hashKey(cacheKey))
 
 			// Try to get cached response
+			ctx, span := startSpan(c.Request.Context(), obs.tracer, spanLookup, keyHash, attrResource.String(baseURL))
 			var cachedBytes []byte
-			err := cache.Get(c.Request.Context(), cacheKey, &cachedBytes)
+			var gzipped bool
+			var err error
 
-			if err != nil {
-				config.Logger("setOrGetCache.get cacheKey", err)
+			// Compressed entries can be sent as-is to clients that accept gzip
+			gzipCache, canGzip := cache.(GzipGetter)
+			if canGzip && acceptsGzip(c.GetHeader("Accept-Encoding")) {
+				cachedBytes, gzipped, err = gzipCache.GetGzip(ctx, cacheKey)
+			} else {
+				err = cache.Get(ctx, cacheKey, &cachedBytes)
 			}
 
+			switch {
 			// Serve from cache if available
-			if err == nil && len(cachedBytes) > 0 {
+			case err == nil && len(cachedBytes) > 0:
+				endSpan(span, OutcomeHit, nil)
+				obs.event(c, start, cacheEvent{outcome: OutcomeHit, key: cacheKey, resource: baseURL})
+				if canGzip {
+					c.Header("Vary", "Accept-Encoding")
+				}
+				if gzipped {
+					c.Header("Content-Encoding", "gzip")
+				}
+				if method == "HEAD" {
+					c.Header("Content-Type", "application/json; charset=utf-8")
+					c.Header("Content-Length", strconv.Itoa(len(cachedBytes)))
+					c.AbortWithStatus(http.StatusOK)
+					return
+				}
 				c.Data(http.StatusOK, "application/json; charset=utf-8", cachedBytes)
 				c.Abort()
 				return
+
+			// A miss is the expected path, only real failures are worth logging
+			case err != nil && !errors.Is(err, ErrCacheMiss):
+				endSpan(span, OutcomeError, err)
+				obs.event(c, start, cacheEvent{outcome: OutcomeError, op: "setOrGetCache.get cacheKey", key: cacheKey, resource: baseURL, err: err})
+
+			// Every lookup records exactly one outcome, so errors aren't also counted as misses
+			default:
+				endSpan(span, OutcomeMiss, nil)
+				obs.event(c, start, cacheEvent{outcome: OutcomeMiss, key: cacheKey, resource: baseURL})
+			}
+
+			if method == "HEAD" {
+				next()
+
+				invalidator.invalidateAfter(c, start)
+				return
 			}
 
 			// Cache miss: capture response for caching
@@ -149,19 +414,48 @@ func SetOrGetCache(cache Cache, config CacheConfig) gin.HandlerFunc {
 			}
 			c.Writer = writer
 
-			c.Next()
+			next()
 
-			// Cache successful responses only
-			if writer.Status() == http.StatusOK && writer.body.Len() > 0 {
-				err = cache.Set(c.Request.Context(), cacheKey, writer.body.Bytes(), config.TTL)
+			// Cache successful responses only, unless the handler called Skip
+			// Responses setting cookies are never stored, they would hand one user's session to others
+			ctl := controlOf(c, false)
+			if writer.Status() == http.StatusOK && writer.body.Len() > 0 && !ctl.skip && writer.Header().Get("Set-Cookie") == "" {
+				ttl := config.TTL
+				if ctl.ttl > 0 {
+					ttl = ctl.ttl
+				}
+
+				ctx, span = startSpan(c.Request.Context(), obs.tracer, spanStore, keyHash, attrResource.String(baseURL), attrBodySize.Int(writer.body.Len()))
+				err = cache.Set(ctx, cacheKey, writer.body.Bytes(), ttl)
+				if err == nil && len(ctl.tags) > 0 {
+					if err = tag(ctx, cache, cacheKey, ttl, invalidator.namespaced(ctl.tags)...); err != nil {
+						// An untagged entry could outlive a tag purge, so don't keep it
+						_ = cache.Del(ctx, cacheKey)
+					}
+				}
+				if err == nil && keyPrefix != "" {
+					if err = indexPrefix(ctx, cache, invalidator.prefixIndex, keyPrefix, ttl); err != nil {
+						// Invalidations of exact keys only find the prefixes in the index
+						_ = cache.Del(ctx, cacheKey)
+					}
+				}
 				if err != nil {
-					config.Logger("setOrGetCache.set cacheKey", err)
+					endSpan(span, OutcomeError, err)
+					obs.event(c, start, cacheEvent{outcome: OutcomeError, op: "setOrGetCache.set cacheKey", key: cacheKey, resource: baseURL, err: err})
+				} else {
+					endSpan(span, OutcomeStore, nil)
+					obs.event(c, start, cacheEvent{outcome: OutcomeStore, key: cacheKey, resource: baseURL, size: writer.body.Len()})
 				}
 			}
+
+			invalidator.invalidateAfter(c, start)
 			return
 		}
 
 		// Pass through for other HTTP methods
-		c.Next()
+		obs.event(c, start, cacheEvent{outcome: OutcomeBypass, key: path, resource: baseURL})
+		next()
+
+		invalidator.invalidateAfter(c, start)
 	}
 }
@@ -1,10 +1,13 @@
 package cache
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -604,3 +607,228 @@ func TestMiddleware_NonOKStatus_DoesNotCache(t *testing.T) {
 	err = cache.DelWildCard(context.Background(), "/v1/*")
 	assert.NoError(t, err)
 }
+
+// TestMiddleware_CacheMiss_IsNotLogged tests that cache misses are not reported as errors
+func TestMiddleware_CacheMiss_IsNotLogged(t *testing.T) {
+	cfg := RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Password: "",
+		Database: 0,
+	}
+	cache, err := NewRedisCache(cfg)
+	assert.NoError(t, err)
+
+	var logged []string
+	config := CacheConfig{
+		TTL:      10 * time.Second,
+		Groups:   map[string][]string{},
+		Outdoors: []string{},
+		Logger: func(message string, args ...interface{}) {
+			logged = append(logged, message)
+		},
+	}
+
+	router := setupTestRouter(cache, config)
+
+	router.GET("/v1/product/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "product"})
+	})
+
+	// First request - cache miss, should not log anything
+	req1 := httptest.NewRequest("GET", "/v1/product/miss", nil)
+	w1 := httptest.NewRecorder()
+	router.ServeHTTP(w1, req1)
+	assert.Equal(t, http.StatusOK, w1.Code)
+	assert.Empty(t, logged, "cache miss should not be logged")
+
+	// Second request - cache hit, should not log anything
+	req2 := httptest.NewRequest("GET", "/v1/product/miss", nil)
+	w2 := httptest.NewRecorder()
+	router.ServeHTTP(w2, req2)
+	assert.Equal(t, http.StatusOK, w2.Code)
+	assert.Empty(t, logged, "cache hit should not be logged")
+
+	// Cleanup
+	err = cache.DelWildCard(context.Background(), "/v1/*")
+	assert.NoError(t, err)
+}
+
+// TestMiddleware_Slog_StructuredEvents tests that cache events are emitted as structured slog records
+func TestMiddleware_Slog_StructuredEvents(t *testing.T) {
+	cfg := RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Password: "",
+		Database: 0,
+	}
+	cache, err := NewRedisCache(cfg)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	config := CacheConfig{
+		TTL:      10 * time.Second,
+		Groups:   map[string][]string{},
+		Outdoors: []string{},
+		Slog:     slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		LogLevels: map[Outcome]slog.Level{
+			OutcomeStore: slog.LevelInfo,
+		},
+	}
+
+	router := setupTestRouter(cache, config)
+
+	router.GET("/v1/product/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "product"})
+	})
+
+	req1 := httptest.NewRequest("GET", "/v1/product/slog", nil)
+	req1.Header.Set("X-Request-ID", "req-1")
+	router.ServeHTTP(httptest.NewRecorder(), req1)
+
+	req2 := httptest.NewRequest("GET", "/v1/product/slog", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req2)
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		err = json.Unmarshal([]byte(line), &record)
+		assert.NoError(t, err)
+		records = append(records, record)
+	}
+
+	if assert.Len(t, records, 3, "expected miss, store and hit events") {
+		assert.Equal(t, "miss", records[0]["outcome"])
+		assert.Equal(t, "DEBUG", records[0]["level"])
+		assert.Equal(t, "/v1/product/slog", records[0]["key"])
+		assert.Equal(t, "GET", records[0]["method"])
+		assert.Equal(t, "/v1/product/:id", records[0]["route"])
+		assert.Equal(t, "product", records[0]["resource"])
+		assert.Equal(t, "req-1", records[0]["request_id"])
+		assert.Contains(t, records[0], "duration")
+
+		assert.Equal(t, "store", records[1]["outcome"])
+		assert.Equal(t, "INFO", records[1]["level"], "store level should be overridden")
+
+		assert.Equal(t, "hit", records[2]["outcome"])
+		assert.NotContains(t, records[2], "request_id")
+	}
+
+	// Cleanup
+	err = cache.DelWildCard(context.Background(), "/v1/*")
+	assert.NoError(t, err)
+}
+
+// TestMiddleware_Slog_SamplesHitMiss tests that hit and miss events are sampled
+func TestMiddleware_Slog_SamplesHitMiss(t *testing.T) {
+	cfg := RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Password: "",
+		Database: 0,
+	}
+	cache, err := NewRedisCache(cfg)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	config := CacheConfig{
+		TTL:           10 * time.Second,
+		Groups:        map[string][]string{},
+		Outdoors:      []string{},
+		Slog:          slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		LogSampleRate: 5,
+	}
+
+	router := setupTestRouter(cache, config)
+
+	router.GET("/v1/product/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "product"})
+	})
+
+	// One miss followed by nine hits, only every fifth hit/miss event is logged
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest("GET", "/v1/product/sampled", nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, 2, strings.Count(buf.String(), `"outcome":"hit"`)+strings.Count(buf.String(), `"outcome":"miss"`))
+	assert.Equal(t, 1, strings.Count(buf.String(), `"outcome":"store"`), "store events should not be sampled")
+
+	// Cleanup
+	err = cache.DelWildCard(context.Background(), "/v1/*")
+	assert.NoError(t, err)
+}
+
+// TestMiddleware_HeadRequest_ServedFromGetEntry tests that HEAD requests are answered from the GET entry without a body
+func TestMiddleware_HeadRequest_ServedFromGetEntry(t *testing.T) {
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	_ = cache.Del(ctx, "/v1/headitem")
+
+	router := setupTestRouter(cache, CacheConfig{TTL: 10 * time.Second})
+
+	callCount := map[string]int{}
+	handler := func(c *gin.Context) {
+		callCount[c.Request.Method]++
+		c.JSON(http.StatusOK, gin.H{"message": "head"})
+	}
+	router.GET("/v1/headitem", handler)
+	router.HEAD("/v1/headitem", handler)
+
+	// A HEAD miss reaches the handler and stores nothing
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("HEAD", "/v1/headitem", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, callCount["HEAD"])
+
+	var cached []byte
+	assert.ErrorIs(t, cache.Get(ctx, "/v1/headitem", &cached), ErrCacheMiss)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/headitem", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("HEAD", "/v1/headitem", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, strconv.Itoa(len(body)), w.Header().Get("Content-Length"))
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, map[string]int{"GET": 1, "HEAD": 1}, callCount)
+
+	_ = cache.Del(ctx, "/v1/headitem")
+}
+
+// TestMiddleware_HeadRequest_GetOnlyRoute tests that HEAD on a route without a HEAD handler gets the same status with and without a cached GET entry
+func TestMiddleware_HeadRequest_GetOnlyRoute(t *testing.T) {
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	_ = cache.Del(ctx, "/v1/getonly")
+
+	router := setupTestRouter(cache, CacheConfig{TTL: 10 * time.Second})
+	router.GET("/v1/getonly", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "get"})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("HEAD", "/v1/getonly", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/getonly", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var cached []byte
+	assert.NoError(t, cache.Get(ctx, "/v1/getonly", &cached))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("HEAD", "/v1/getonly", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code, "a cached GET entry should not change the status")
+
+	_ = cache.Del(ctx, "/v1/getonly")
+}
@@ -0,0 +1,31 @@
+package cache
+
+// versionSeparator separates the namespace from the version in key prefixes, e.g. "shop@v42:"
+const versionSeparator = "@"
+
+// keyNamespace returns the prefix of every key written by the middleware
+// "shop" becomes "shop:", "shop" with version "v42" becomes "shop@v42:", and no namespace
+// and no version keep keys unprefixed
+func keyNamespace(namespace, version string) string {
+	switch {
+	case namespace == "" && version == "":
+		return ""
+	case version == "":
+		return namespace + ":"
+	default:
+		return namespace + versionSeparator + version + ":"
+	}
+}
+
+// namespaced prefixes tags with the namespace
+func (inv *Invalidator) namespaced(tags []string) []string {
+	if inv.namespace == "" {
+		return tags
+	}
+
+	prefixed := make([]string, len(tags))
+	for i, tag := range tags {
+		prefixed[i] = inv.namespace + tag
+	}
+	return prefixed
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNamespace_Isolation tests that middleware instances with different namespaces never share or invalidate entries
+func TestNamespace_Isolation(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+
+	callCount := map[string]int{}
+	newRouter := func(config CacheConfig) *gin.Engine {
+		name := config.Namespace + config.Version
+		config.TTL = 10 * time.Second
+		router := setupTestRouter(cache, config)
+		router.GET("/v1/product", func(c *gin.Context) {
+			callCount[name]++
+			AddTags(c, "catalog")
+			c.JSON(http.StatusOK, gin.H{"service": name})
+		})
+		router.POST("/v1/product", func(c *gin.Context) {
+			c.JSON(http.StatusCreated, gin.H{"message": "created"})
+		})
+		return router
+	}
+
+	// Glob characters in the namespace must not match other namespaces
+	shop := newRouter(CacheConfig{Namespace: "shop[1]"})
+	shopOther := newRouter(CacheConfig{Namespace: "shop1"})
+	shopNext := newRouter(CacheConfig{Namespace: "shop[1]", Version: "v2"})
+
+	get := func(router *gin.Engine) string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/product", nil))
+		return w.Body.String()
+	}
+
+	for range 2 {
+		assert.Contains(t, get(shop), `"shop[1]"`)
+		assert.Contains(t, get(shopOther), `"shop1"`)
+		assert.Contains(t, get(shopNext), `"shop[1]v2"`)
+	}
+	assert.Equal(t, map[string]int{"shop[1]": 1, "shop1": 1, "shop[1]v2": 1}, callCount)
+
+	exists, err := client.Exists(ctx, "shop[1]:/v1/product", "shop1:/v1/product", "shop[1]@v2:/v1/product").Result()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), exists)
+
+	// Invalidation stays inside the namespace
+	shop.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/product", nil))
+	get(shop)
+	get(shopOther)
+	get(shopNext)
+	assert.Equal(t, map[string]int{"shop[1]": 2, "shop1": 1, "shop[1]v2": 1}, callCount)
+
+	// So do tags
+	report, err := NewInvalidator(cache, CacheConfig{Namespace: "shop1"}).InvalidateTags(ctx, "catalog")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), report.Deleted())
+	get(shop)
+	get(shopOther)
+	assert.Equal(t, map[string]int{"shop[1]": 2, "shop1": 2, "shop[1]v2": 1}, callCount)
+
+	// Cleanup
+	for _, namespace := range []string{"shop[1]:", "shop1:", "shop[1]@v2:"} {
+		assert.NoError(t, cache.DelWildCard(ctx, escapeGlob(namespace)+"*"))
+	}
+	assert.NoError(t, cache.DelWildCard(ctx, "tag:*"))
+}
+
+// TestKeyNamespace tests the key prefix format
+func TestKeyNamespace(t *testing.T) {
+	assert.Equal(t, "", keyNamespace("", ""))
+	assert.Equal(t, "shop:", keyNamespace("shop", ""))
+	assert.Equal(t, "shop@v42:", keyNamespace("shop", "v42"))
+	assert.Equal(t, "@v42:", keyNamespace("", "v42"))
+}
@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NestedRoute configures invalidation of a nested route such as "/v1/shop/:shop_id/product/:id"
+// A mutation of a nested route invalidates the resource named by its last static segment,
+// both inside the parent scope and at the top level
+type NestedRoute struct {
+	// KeepScoped leaves the parent-scoped collection, e.g. "/v1/shop/7/product*", cached
+	KeepScoped bool
+
+	// KeepTopLevel leaves the top-level resource, e.g. "/v1/product*", cached
+	KeepTopLevel bool
+}
+
+// requestScope is what a request resolves to for logging, metrics and invalidation
+type requestScope struct {
+	// resource is the resource type, e.g. "product" for "/v1/product/3" and "/v1/shop/7/product/3"
+	resource string
+
+	// collections are the collection paths a mutation clears,
+	// e.g. "/v1/shop/7/product" and "/v1/product" for a nested route
+	collections []string
+
+	// id is the item id on item routes when EntityInvalidation is on
+	id string
+}
+
+// resolve resolves the resource, collections and item id of the request in c
+// Routes not listed in NestedRoutes resolve to the segment after the version, like getBaseURL
+func (inv *Invalidator) resolve(c *gin.Context) requestScope {
+	return inv.resolveRoute(c.Request.URL.Path, c.FullPath())
+}
+
+// resolvePath resolves a path without a request, matching it against the NestedRoutes patterns
+// The item id is never resolved, so the whole resource is cleared like for collection routes
+func (inv *Invalidator) resolvePath(path string) requestScope {
+	routes := slices.Sorted(maps.Keys(inv.nestedRoutes))
+	for _, route := range routes {
+		if matchRoute(route, path) {
+			scope := inv.resolveRoute(path, route)
+			scope.id = ""
+			return scope
+		}
+	}
+
+	return inv.resolveRoute(path, "")
+}
+
+// resolveRoute resolves the scope of path served by the gin route pattern route
+func (inv *Invalidator) resolveRoute(path, route string) requestScope {
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	routeSegments := strings.Split(strings.Trim(route, "/"), "/")
+
+	index := 1
+	nested, isNested := inv.nestedRoutes[route]
+	if isNested {
+		index = lastStaticSegment(routeSegments)
+	}
+
+	// Paths too short to name a resource keep the legacy resolution
+	if index < 1 || index >= len(pathSegments) {
+		resource := getBaseURL(path)
+		return requestScope{resource: resource, collections: []string{collectionPath(resource)}}
+	}
+
+	scope := requestScope{resource: pathSegments[index]}
+
+	if !isNested {
+		scope.collections = []string{collectionPath(scope.resource)}
+	} else {
+		if !nested.KeepScoped {
+			scope.collections = append(scope.collections, "/"+strings.Join(pathSegments[:index+1], "/"))
+		}
+		if !nested.KeepTopLevel {
+			scope.collections = append(scope.collections, collectionPath(scope.resource))
+		}
+	}
+
+	// An item route has an item param right after the resource segment
+	if inv.entityInvalidation && index+1 < len(routeSegments) && index+1 < len(pathSegments) {
+		if param, ok := strings.CutPrefix(routeSegments[index+1], ":"); ok && slices.Contains(inv.itemParams, param) {
+			scope.id = pathSegments[index+1]
+		}
+	}
+
+	return scope
+}
+
+// lastStaticSegment returns the index of the last route segment that isn't a param
+func lastStaticSegment(segments []string) int {
+	for i := len(segments) - 1; i >= 0; i-- {
+		if !strings.HasPrefix(segments[i], ":") && !strings.HasPrefix(segments[i], "*") {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchRoute reports whether path is served by the gin route pattern route
+func matchRoute(route, path string) bool {
+	routeSegments := strings.Split(strings.Trim(route, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, segment := range routeSegments {
+		switch {
+		case strings.HasPrefix(segment, "*"):
+			return true
+		case i >= len(pathSegments):
+			return false
+		case strings.HasPrefix(segment, ":"):
+			if pathSegments[i] == "" {
+				return false
+			}
+		case segment != pathSegments[i]:
+			return false
+		}
+	}
+
+	return len(routeSegments) == len(pathSegments)
+}
@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMiddleware_NestedRoutes tests that nested mutations clear the parent-scoped collection and the top-level resource
+func TestMiddleware_NestedRoutes(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	router := setupTestRouter(cache, CacheConfig{
+		TTL: 10 * time.Second,
+		NestedRoutes: map[string]NestedRoute{
+			"/v1/shop/:shop_id/product/:id": {},
+			"/v1/shop/:shop_id/review":      {KeepTopLevel: true},
+		},
+	})
+
+	handler := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	}
+	router.PUT("/v1/shop/:shop_id/product/:id", handler)
+	router.POST("/v1/shop/:shop_id/review", handler)
+
+	seed := func() {
+		for _, key := range []string{
+			"/v1/shop/7", "/v1/shop/7/product", "/v1/shop/7/product/3", "/v1/shop/8/product",
+			"/v1/product/3", "/v1/shop/7/review", "/v1/review",
+		} {
+			assert.NoError(t, cache.Set(ctx, key, []byte(`{}`), 10*time.Second))
+		}
+	}
+	cached := func(key string) bool {
+		var body []byte
+		return cache.Get(ctx, key, &body) == nil
+	}
+
+	seed()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/v1/shop/7/product/3", nil))
+
+	assert.False(t, cached("/v1/shop/7/product"), "parent-scoped collection should be invalidated")
+	assert.False(t, cached("/v1/shop/7/product/3"))
+	assert.False(t, cached("/v1/product/3"), "top-level resource should be invalidated")
+	assert.True(t, cached("/v1/shop/7"), "parent resource should be kept")
+	assert.True(t, cached("/v1/shop/8/product"), "other parents should be kept")
+
+	// KeepTopLevel only clears the scoped collection
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/shop/7/review", nil))
+	assert.False(t, cached("/v1/shop/7/review"))
+	assert.True(t, cached("/v1/review"))
+
+	// Cleanup
+	assert.NoError(t, cache.DelWildCard(ctx, "/v1/*"))
+}
+
+// TestInvalidator_Resolve tests resource resolution of nested and flat routes
+func TestInvalidator_Resolve(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	invalidator := NewInvalidator(nil, CacheConfig{
+		EntityInvalidation: true,
+		NestedRoutes: map[string]NestedRoute{
+			"/v1/shop/:shop_id/product/:id": {KeepScoped: true},
+		},
+	})
+
+	var scopes []requestScope
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		scopes = append(scopes, invalidator.resolve(c))
+	})
+	router.PUT("/v1/shop/:shop_id/product/:id", func(c *gin.Context) {})
+	router.PUT("/v1/store/:id", func(c *gin.Context) {})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/v1/shop/7/product/3", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/v1/store/7", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/health", nil))
+
+	assert.Equal(t, []requestScope{
+		{resource: "product", collections: []string{"/v1/product"}, id: "3"},
+		{resource: "store", collections: []string{"/v1/store"}, id: "7"},
+		{resource: "", collections: []string{"/v1/"}},
+	}, scopes)
+}
+
+// TestInvalidator_PathNested tests that InvalidatePath resolves nested routes like the middleware
+func TestInvalidator_PathNested(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	invalidator := NewInvalidator(cache, CacheConfig{
+		EntityInvalidation: true,
+		NestedRoutes: map[string]NestedRoute{
+			"/v1/shop/:shop_id/product/:id": {},
+		},
+	})
+
+	keys := []string{"/v1/shop/7", "/v1/shop/7/product", "/v1/shop/7/product/3", "/v1/shop/7/product/4", "/v1/product", "/v1/shop/8/product"}
+	for _, key := range keys {
+		assert.NoError(t, cache.Set(ctx, key, []byte(`{}`), 10*time.Second))
+	}
+	cached := func(key string) bool {
+		var body []byte
+		return cache.Get(ctx, key, &body) == nil
+	}
+
+	report, err := invalidator.InvalidatePath(ctx, "/v1/shop/7/product/3")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), report.Deleted())
+
+	assert.True(t, cached("/v1/shop/7"), "parent resource should be kept")
+	assert.True(t, cached("/v1/shop/8/product"), "other parents should be kept")
+	for _, key := range []string{"/v1/shop/7/product", "/v1/shop/7/product/3", "/v1/shop/7/product/4", "/v1/product"} {
+		assert.False(t, cached(key), key)
+	}
+
+	// Paths that don't match a nested route resolve to their first segment
+	assert.Equal(t, "shop", invalidator.resolvePath("/v1/shop/7/review").resource)
+	assert.True(t, matchRoute("/v1/files/*path", "/v1/files/a/b"))
+	assert.False(t, matchRoute("/v1/shop/:shop_id/product/:id", "/v1/shop/7/product"))
+
+	// Cleanup
+	assert.NoError(t, cache.DelWildCard(ctx, "/v1/*"))
+}
+
+// TestCacheConfig_ValidateNestedRoutes tests that nested resources count as served and unknown routes are reported
+func TestCacheConfig_ValidateNestedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/v1/shop/:shop_id/product", func(c *gin.Context) {})
+
+	config := CacheConfig{
+		Groups: map[string][]string{
+			"shop": {"product"},
+		},
+		NestedRoutes: map[string]NestedRoute{
+			"/v1/shop/:shop_id/product": {},
+		},
+	}
+	assert.NoError(t, config.Validate(router.Routes()))
+
+	config.NestedRoutes["/v1/shop/:shop/product"] = NestedRoute{}
+	err := config.Validate(router.Routes())
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `nested route "/v1/shop/:shop/product" is not registered`)
+	}
+}
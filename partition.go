@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// partitionKeyPrefix starts the keys of partitioned responses
+// It is followed by a hash of the partition and a colon, e.g. "p:9b1c…:/v1/product"
+const partitionKeyPrefix = "p:"
+
+// partitionContextKey is the context key set by WithPartition
+type partitionContextKey struct{}
+
+// WithPartition scopes Invalidator calls made with the returned context to a single partition
+// Without it programmatic invalidations clear the entries of every partition
+func WithPartition(ctx context.Context, partition string) context.Context {
+	return context.WithValue(ctx, partitionContextKey{}, partition)
+}
+
+// PartitionByHeader partitions by a request header, e.g. "X-Tenant-ID"
+func PartitionByHeader(name string) func(c *gin.Context) string {
+	return func(c *gin.Context) string {
+		return c.GetHeader(name)
+	}
+}
+
+// PartitionByContext partitions by a gin context value set by an earlier middleware, e.g. c.Set("tenant", id)
+func PartitionByContext(key string) func(c *gin.Context) string {
+	return func(c *gin.Context) string {
+		value, ok := c.Get(key)
+		if !ok || value == nil {
+			return ""
+		}
+		return fmt.Sprint(value)
+	}
+}
+
+// PurgePartition deletes every cached response of a partition, e.g. on logout or a permission change
+func (inv *Invalidator) PurgePartition(ctx context.Context, partition string) (InvalidationReport, error) {
+	target := invalidationTarget{op: "invalidator.delWildCard partition", pattern: escapeGlob(inv.namespace) + partitionPrefix(partition) + "*"}
+	return inv.invalidateTargets(ctx, nil, time.Now(), []invalidationTarget{target})
+}
+
+// partitionPrefix returns the key prefix of a partition
+// The partition is hashed so tenant and user IDs never appear in keys
+func partitionPrefix(partition string) string {
+	if partition == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(partition))
+	return partitionKeyPrefix + hex.EncodeToString(sum[:16]) + ":"
+}
+
+// partitionOf returns the partition of an invalidation
+// The request partition wins over one set with WithPartition
+func (inv *Invalidator) partitionOf(ctx context.Context, c *gin.Context) string {
+	if c != nil && inv.partition != nil {
+		return inv.partition(c)
+	}
+
+	partition, _ := ctx.Value(partitionContextKey{}).(string)
+	return partition
+}
+
+// keyPrefixes returns the prefixes of every key variant an invalidation in partition must clear
+// An unpartitioned invalidation clears every partition, a partitioned one only its own
+// "p:*:" also matches per-user keys inside a partition, so no combined variant is needed
+func (inv *Invalidator) keyPrefixes(partition string) []string {
+	if partition != "" {
+		prefixes := []string{partitionPrefix(partition)}
+		if inv.perUser {
+			prefixes = append(prefixes, partitionPrefix(partition)+userKeyVariant)
+		}
+		return prefixes
+	}
+
+	prefixes := []string{""}
+	if inv.partition != nil {
+		prefixes = append(prefixes, partitionKeyPrefix+"*:")
+	}
+	if inv.perUser {
+		prefixes = append(prefixes, userKeyVariant)
+	}
+	return prefixes
+}
+
+// prefixIndexKeyPrefix prefixes the sorted set of partition and user key prefixes in use,
+// scored by when their last entry expires. It never matches the "/v1/" invalidation patterns.
+const prefixIndexKeyPrefix = "prefixes:"
+
+// PrefixIndexer is implemented by caches that index the partition and user key prefixes in use
+// Invalidations of exact keys then delete every variant directly instead of matching them with KEYS
+type PrefixIndexer interface {
+	// IndexPrefix records that an entry with prefix lives in index for ttl
+	IndexPrefix(ctx context.Context, index, prefix string, ttl time.Duration) error
+
+	// IndexedPrefixes returns the prefixes in index that still have live entries
+	IndexedPrefixes(ctx context.Context, index string) ([]string, error)
+}
+
+// IndexPrefix adds prefix to the sorted set, keeping the latest expiry as its score
+// Expired prefixes are trimmed on every write, and the set expires with its longest-lived member
+func (r *redisCache) IndexPrefix(ctx context.Context, index, prefix string, ttl time.Duration) error {
+	if err := r.ready(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expires := math.Inf(1)
+	if ttl > 0 {
+		expires = float64(now.Add(ttl).UnixMilli())
+	}
+
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAddGT(ctx, index, redis.Z{Score: expires, Member: prefix})
+		pipe.ZRemRangeByScore(ctx, index, "-inf", "("+strconv.FormatInt(now.UnixMilli(), 10))
+		if ttl > 0 {
+			// NX sets the expiry of a new set, GT only ever extends it
+			pipe.ExpireNX(ctx, index, ttl)
+			pipe.ExpireGT(ctx, index, ttl)
+		} else {
+			pipe.Persist(ctx, index)
+		}
+		return nil
+	})
+	return r.backendError(err)
+}
+
+// IndexedPrefixes returns the prefixes whose entries haven't all expired yet
+func (r *redisCache) IndexedPrefixes(ctx context.Context, index string) ([]string, error) {
+	if err := r.ready(); err != nil {
+		return nil, err
+	}
+
+	prefixes, err := r.client.ZRangeByScore(ctx, index, &redis.ZRangeBy{
+		Min: strconv.FormatInt(time.Now().UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+	return prefixes, r.backendError(err)
+}
+
+// IndexPrefix indexes the prefix on the inner cache
+func (s *secureCache) IndexPrefix(ctx context.Context, index, prefix string, ttl time.Duration) error {
+	return indexPrefix(ctx, s.inner, index, prefix, ttl)
+}
+
+// IndexedPrefixes reads the prefix index of the inner cache
+func (s *secureCache) IndexedPrefixes(ctx context.Context, index string) ([]string, error) {
+	return indexedPrefixes(ctx, s.inner, index)
+}
+
+// errPrefixIndexUnsupported is returned for prefix index operations on caches that don't implement PrefixIndexer
+var errPrefixIndexUnsupported = fmt.Errorf("cache: prefix index: %w", errors.ErrUnsupported)
+
+// indexPrefix indexes a key prefix on any Cache
+func indexPrefix(ctx context.Context, cache Cache, index, prefix string, ttl time.Duration) error {
+	if indexer, ok := cache.(PrefixIndexer); ok {
+		return indexer.IndexPrefix(ctx, index, prefix, ttl)
+	}
+	return errPrefixIndexUnsupported
+}
+
+// indexedPrefixes reads the key prefixes in use on any Cache
+func indexedPrefixes(ctx context.Context, cache Cache, index string) ([]string, error) {
+	if indexer, ok := cache.(PrefixIndexer); ok {
+		return indexer.IndexedPrefixes(ctx, index)
+	}
+	return nil, errPrefixIndexUnsupported
+}
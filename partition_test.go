@@ -0,0 +1,259 @@
+package cache
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPartition_TenantIsolation tests that tenants are cached and invalidated separately
+func TestPartition_TenantIsolation(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+
+	config := CacheConfig{
+		TTL:       10 * time.Second,
+		Partition: PartitionByHeader("X-Tenant-ID"),
+	}
+	router := setupTestRouter(cache, config)
+
+	callCount := map[string]int{}
+	router.GET("/v1/product", func(c *gin.Context) {
+		tenant := c.GetHeader("X-Tenant-ID")
+		callCount[tenant]++
+		c.JSON(http.StatusOK, gin.H{"tenant": tenant})
+	})
+	router.POST("/v1/product", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"message": "created"})
+	})
+
+	serve := func(method, tenant string) string {
+		req := httptest.NewRequest(method, "/v1/product", nil)
+		if tenant != "" {
+			req.Header.Set("X-Tenant-ID", tenant)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	assert.Contains(t, serve("GET", "a"), `"a"`)
+	assert.Contains(t, serve("GET", "b"), `"b"`)
+	assert.Contains(t, serve("GET", "a"), `"a"`, "tenants should not see each other's entries")
+	serve("GET", "")
+	assert.Equal(t, map[string]int{"a": 1, "b": 1, "": 1}, callCount)
+
+	keys, err := client.Keys(ctx, "p:*").Result()
+	assert.NoError(t, err)
+	assert.Len(t, keys, 2)
+	for _, key := range keys {
+		assert.NotContains(t, key, ":a:", "partition should be hashed")
+	}
+
+	// Tenant a's mutation leaves tenant b and unpartitioned entries alone
+	serve("POST", "a")
+	serve("GET", "a")
+	serve("GET", "b")
+	serve("GET", "")
+	assert.Equal(t, map[string]int{"a": 2, "b": 1, "": 1}, callCount)
+
+	// An unpartitioned mutation clears every partition
+	serve("POST", "")
+	serve("GET", "a")
+	serve("GET", "b")
+	serve("GET", "")
+	assert.Equal(t, map[string]int{"a": 3, "b": 2, "": 2}, callCount)
+
+	// Programmatic invalidation can be scoped to a partition
+	invalidator := NewInvalidator(cache, config)
+	report, err := invalidator.InvalidateResource(WithPartition(ctx, "b"), "product")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), report.Deleted())
+
+	// PurgePartition clears everything of one partition
+	assert.NoError(t, cache.Set(ctx, partitionPrefix("a")+"/v1/category", []byte(`{}`), 10*time.Second))
+	report, err = invalidator.PurgePartition(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), report.Deleted())
+
+	keys, err = client.Keys(ctx, "p:*").Result()
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	// Cleanup
+	assert.NoError(t, cache.DelWildCard(ctx, "/v1/*"))
+}
+
+// TestPartition_Credentials tests that a partition never lets credentialed responses reach other callers,
+// e.g. with the cache registered before a per-route authentication middleware
+func TestPartition_Credentials(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"alice"}`))
+	valid := "Bearer eyJhbGciOiJIUzI1NiJ9." + payload + ".valid"
+	forged := "Bearer eyJhbGciOiJIUzI1NiJ9." + payload + ".forged"
+
+	// authenticate only accepts the valid token and sets the verified subject
+	authenticate := func(c *gin.Context) {
+		if c.GetHeader("Authorization") != valid {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Set("user", "alice")
+	}
+
+	for name, config := range map[string]CacheConfig{
+		"tenant header":           {Partition: PartitionByHeader("X-Tenant-ID")},
+		"verified subject":        {Partition: PartitionByContext("user")},
+		"tenant header, per user": {Partition: PartitionByHeader("X-Tenant-ID"), PerUserKeys: []Rule{{Route: "/v1/me"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config.TTL = 10 * time.Second
+			router := setupTestRouter(cache, config)
+			router.GET("/v1/me", authenticate, func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"user": c.GetString("user")})
+			})
+
+			serve := func(authorization string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest("GET", "/v1/me", nil)
+				req.Header.Set("X-Tenant-ID", "t1")
+				if authorization != "" {
+					req.Header.Set("Authorization", authorization)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				return w
+			}
+
+			for range 2 {
+				w := serve(valid)
+				assert.Equal(t, http.StatusOK, w.Code)
+				assert.Contains(t, w.Body.String(), "alice")
+			}
+
+			for _, authorization := range []string{forged, ""} {
+				w := serve(authorization)
+				assert.Equal(t, http.StatusUnauthorized, w.Code, "%q should not get a cached response", authorization)
+				assert.NotContains(t, w.Body.String(), "alice")
+			}
+
+			keys, err := client.Keys(ctx, "*/v1/me").Result()
+			assert.NoError(t, err)
+			if len(config.PerUserKeys) == 0 {
+				assert.Empty(t, keys, "credentialed responses should bypass the cache")
+			}
+			if len(keys) > 0 {
+				client.Del(ctx, keys...)
+			}
+		})
+	}
+}
+
+// TestPartition_Extractors tests the built-in partition extractors
+func TestPartition_Extractors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/v1/product", nil)
+
+	assert.Empty(t, PartitionByHeader("X-Tenant-ID")(c))
+	assert.Empty(t, PartitionByContext("tenant")(c))
+
+	c.Request.Header.Set("X-Tenant-ID", "t1")
+	assert.Equal(t, "t1", PartitionByHeader("X-Tenant-ID")(c))
+
+	c.Set("tenant", 7)
+	assert.Equal(t, "7", PartitionByContext("tenant")(c))
+}
+
+// TestInvalidator_KeyPrefixes tests the key variants cleared for partitions and per-user keys
+func TestInvalidator_KeyPrefixes(t *testing.T) {
+	inv := NewInvalidator(nil, CacheConfig{})
+	assert.Equal(t, []string{""}, inv.keyPrefixes(""))
+
+	inv = NewInvalidator(nil, CacheConfig{
+		Partition:   PartitionByHeader("X-Tenant-ID"),
+		PerUserKeys: []Rule{{Route: "/v1/cart"}},
+	})
+	assert.Equal(t, []string{"", "p:*:", "u:*:"}, inv.keyPrefixes(""))
+	assert.Equal(t, []string{partitionPrefix("a"), partitionPrefix("a") + "u:*:"}, inv.keyPrefixes("a"))
+}
+
+// TestInvalidator_PrefixIndex tests that exact keys are deleted under every indexed prefix without matching patterns
+func TestInvalidator_PrefixIndex(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+
+	config := CacheConfig{
+		TTL:         10 * time.Second,
+		Partition:   PartitionByHeader("X-Tenant-ID"),
+		PerUserKeys: []Rule{{Route: "/v1/cart"}},
+	}
+	router := setupTestRouter(cache, config)
+	router.GET("/v1/cart", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"owner": c.GetHeader("Authorization")})
+	})
+
+	fill := func() {
+		for _, tenant := range []string{"", "a", "b"} {
+			for _, user := range []string{"", "Bearer alice"} {
+				req := httptest.NewRequest("GET", "/v1/cart", nil)
+				req.Header.Set("X-Tenant-ID", tenant)
+				req.Header.Set("Authorization", user)
+				router.ServeHTTP(httptest.NewRecorder(), req)
+			}
+		}
+	}
+
+	// Other tests index prefixes too
+	client.Del(ctx, prefixIndexKeyPrefix)
+
+	fill()
+	prefixes, err := client.ZRange(ctx, prefixIndexKeyPrefix, 0, -1).Result()
+	assert.NoError(t, err)
+	assert.Len(t, prefixes, 5)
+
+	// One target deletes every variant of the key
+	invalidator := NewInvalidator(cache, config)
+	report, err := invalidator.InvalidateKey(ctx, "/v1/cart")
+	assert.NoError(t, err)
+	assert.Len(t, report.Results, 1)
+	assert.Equal(t, "/v1/cart", report.Results[0].Pattern)
+	assert.Equal(t, int64(6), report.Deleted())
+
+	// A partitioned invalidation only deletes the variants of its partition
+	fill()
+	report, err = invalidator.InvalidateKey(WithPartition(ctx, "a"), "/v1/cart")
+	assert.NoError(t, err)
+	assert.Len(t, report.Results, 1)
+	assert.Equal(t, int64(2), report.Deleted())
+
+	keys, err := client.Keys(ctx, "*/v1/cart").Result()
+	assert.NoError(t, err)
+	assert.Len(t, keys, 4)
+
+	// Cleanup
+	client.Del(ctx, append(keys, prefixIndexKeyPrefix)...)
+}
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// preflightKeyPrefix prefixes cached preflight responses, which never match a resource pattern
+const preflightKeyPrefix = "preflight:"
+
+// preflightRequestHeader prefixes the request headers a preflight response depends on
+const preflightRequestHeader = "Access-Control-Request-"
+
+// preflightEntry is a cached preflight response
+type preflightEntry struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// isPreflight reports whether the request is a CORS preflight
+func isPreflight(c *gin.Context) bool {
+	return c.Request.Method == http.MethodOptions &&
+		c.GetHeader("Origin") != "" &&
+		c.GetHeader("Access-Control-Request-Method") != ""
+}
+
+// preflightKey returns the key of a preflight, i.e. its path and a hash of the Origin and
+// every Access-Control-Request-* header. Requested header lists are compared case-insensitively
+// and in any order.
+func preflightKey(c *gin.Context) string {
+	var names []string
+	for name := range c.Request.Header {
+		if strings.HasPrefix(name, preflightRequestHeader) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	variant := []string{c.GetHeader("Origin")}
+	for _, name := range names {
+		value := strings.Join(c.Request.Header.Values(name), ",")
+		if name == "Access-Control-Request-Headers" {
+			value = normalizeHeaderList(value)
+		}
+		variant = append(variant, name+"="+value)
+	}
+
+	return preflightKeyPrefix + c.Request.URL.Path + ":" + hashKey(strings.Join(variant, "\n"))
+}
+
+// normalizeHeaderList lowercases and sorts a comma-separated list of header names
+func normalizeHeaderList(list string) string {
+	var headers []string
+	for _, header := range strings.Split(list, ",") {
+		if header = strings.ToLower(strings.TrimSpace(header)); header != "" {
+			headers = append(headers, header)
+		}
+	}
+	slices.Sort(headers)
+	return strings.Join(slices.Compact(headers), ",")
+}
+
+// servePreflight answers a preflight from the cache, or runs next and stores its response
+// Only 2xx responses without cookies are stored, unless the handler called Skip
+func servePreflight(c *gin.Context, cache Cache, obs *observer, key string, ttl time.Duration, start time.Time, resource string, next func()) {
+	keyHash := attrKeyHash.String(hashKey(key))
+
+	ctx, span := startSpan(c.Request.Context(), obs.tracer, spanLookup, keyHash, attrResource.String(resource))
+	var data []byte
+	var entry preflightEntry
+	err := cache.Get(ctx, key, &data)
+	if err == nil {
+		if jsonErr := json.Unmarshal(data, &entry); jsonErr != nil {
+			err = fmt.Errorf("%w: %w", ErrDecode, jsonErr)
+		}
+	}
+
+	switch {
+	case err == nil:
+		endSpan(span, OutcomeHit, nil)
+		obs.event(c, start, cacheEvent{outcome: OutcomeHit, key: key, resource: resource})
+		for name, values := range entry.Header {
+			c.Writer.Header()[name] = values
+		}
+		c.AbortWithStatus(entry.Status)
+		if len(entry.Body) > 0 {
+			_, _ = c.Writer.Write(entry.Body)
+		}
+		return
+
+	case !errors.Is(err, ErrCacheMiss):
+		endSpan(span, OutcomeError, err)
+		obs.event(c, start, cacheEvent{outcome: OutcomeError, op: "setOrGetCache.get preflightKey", key: key, resource: resource, err: err})
+
+	default:
+		endSpan(span, OutcomeMiss, nil)
+		obs.event(c, start, cacheEvent{outcome: OutcomeMiss, key: key, resource: resource})
+	}
+
+	writer := &responseWriter{
+		ResponseWriter: c.Writer,
+		body:           bytes.NewBufferString(""),
+	}
+	c.Writer = writer
+
+	next()
+
+	ctl := controlOf(c, false)
+	status := writer.Status()
+	if status < http.StatusOK || status >= http.StatusMultipleChoices || ctl.skip || writer.Header().Get("Set-Cookie") != "" {
+		return
+	}
+	if ctl.ttl > 0 {
+		ttl = ctl.ttl
+	}
+
+	data, err = json.Marshal(preflightEntry{Status: status, Header: writer.Header().Clone(), Body: writer.body.Bytes()})
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrEncode, err)
+		obs.event(c, start, cacheEvent{outcome: OutcomeError, op: "setOrGetCache.set preflightKey", key: key, resource: resource, err: err})
+		return
+	}
+
+	ctx, span = startSpan(c.Request.Context(), obs.tracer, spanStore, keyHash, attrResource.String(resource), attrBodySize.Int(len(data)))
+	if err := cache.Set(ctx, key, data, ttl); err != nil {
+		endSpan(span, OutcomeError, err)
+		obs.event(c, start, cacheEvent{outcome: OutcomeError, op: "setOrGetCache.set preflightKey", key: key, resource: resource, err: err})
+		return
+	}
+	endSpan(span, OutcomeStore, nil)
+	obs.event(c, start, cacheEvent{outcome: OutcomeStore, key: key, resource: resource, size: len(data)})
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPreflight_Cached tests that preflights are cached per origin and requested method and headers
+func TestPreflight_Cached(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+	keys, _ := client.Keys(ctx, "preflight:*").Result()
+	if len(keys) > 0 {
+		client.Del(ctx, keys...)
+	}
+
+	router := setupTestRouter(cache, CacheConfig{TTL: 10 * time.Second, PreflightTTL: 10 * time.Second})
+
+	callCount := 0
+	router.OPTIONS("/v1/product", func(c *gin.Context) {
+		callCount++
+		c.Header("Access-Control-Allow-Origin", c.GetHeader("Origin"))
+		c.Header("Access-Control-Allow-Methods", "GET, POST")
+		c.Header("Access-Control-Allow-Headers", c.GetHeader("Access-Control-Request-Headers"))
+		c.Status(http.StatusNoContent)
+	})
+	router.POST("/v1/product", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"message": "created"})
+	})
+
+	preflight := func(origin, method, headers string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("OPTIONS", "/v1/product", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			req.Header.Set("Access-Control-Request-Headers", headers)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := preflight("https://a.example", "POST", "Content-Type, X-Token")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, 1, callCount)
+
+	// Header lists match in any order and case
+	w = preflight("https://a.example", "POST", "x-token,content-type")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://a.example", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Content-Type, X-Token", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, 1, callCount)
+
+	// Another origin, method or header list is a different entry
+	w = preflight("https://b.example", "POST", "Content-Type, X-Token")
+	assert.Equal(t, "https://b.example", w.Header().Get("Access-Control-Allow-Origin"))
+	preflight("https://a.example", "PUT", "Content-Type, X-Token")
+	preflight("https://a.example", "POST", "")
+	assert.Equal(t, 4, callCount)
+
+	// Mutations never invalidate preflights
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/v1/product", nil))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	preflight("https://a.example", "POST", "Content-Type, X-Token")
+	assert.Equal(t, 4, callCount)
+
+	keys, _ = client.Keys(ctx, "preflight:*").Result()
+	assert.Len(t, keys, 4)
+	client.Del(ctx, keys...)
+}
+
+// TestPreflight_Disabled tests that preflights and plain OPTIONS requests are not cached by default
+func TestPreflight_Disabled(t *testing.T) {
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	router := setupTestRouter(cache, CacheConfig{TTL: 10 * time.Second})
+
+	callCount := 0
+	router.OPTIONS("/v1/product", func(c *gin.Context) {
+		callCount++
+		c.Status(http.StatusNoContent)
+	})
+
+	for range 2 {
+		req := httptest.NewRequest("OPTIONS", "/v1/product", nil)
+		req.Header.Set("Origin", "https://a.example")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	}
+	assert.Equal(t, 2, callCount)
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userKeyPrefix starts the keys of responses cached per user
+// It is followed by a hash of the credentials and a colon, e.g. "u:3f2a…:/v1/user/me"
+const userKeyPrefix = "u:"
+
+// userKeyVariant is the glob prefix matching the per-user variants of every key
+const userKeyVariant = userKeyPrefix + "*:"
+
+// privacy keeps responses of authenticated and sensitive requests from leaking to other users
+type privacy struct {
+	perUser        []compiledRule
+	sessionCookies []string
+	sensitive      func(c *gin.Context) bool
+}
+
+// newPrivacy builds the privacy rules from the middleware configuration
+func newPrivacy(config CacheConfig) *privacy {
+	p := &privacy{
+		sessionCookies: config.SessionCookies,
+		sensitive:      config.Sensitive,
+	}
+
+	for _, rule := range config.PerUserKeys {
+		p.perUser = append(p.perUser, compileRule(rule))
+	}
+
+	return p
+}
+
+// keyPrefix returns the prefix a cacheable read's key gets
+// Anonymous requests get none; credentialed requests get a per-user prefix on PerUserKeys routes
+// and are not cached anywhere else, and Sensitive requests are never cached
+func (p *privacy) keyPrefix(c *gin.Context, resource string) (string, bool) {
+	if p.sensitive != nil && p.sensitive(c) {
+		return "", false
+	}
+
+	credentials := p.credentials(c)
+	if credentials == "" {
+		return "", true
+	}
+
+	for _, rule := range p.perUser {
+		if rule.matches(c, resource) {
+			sum := sha256.Sum256([]byte(credentials))
+			return userKeyPrefix + hex.EncodeToString(sum[:16]) + ":", true
+		}
+	}
+
+	return "", false
+}
+
+// credentials returns the Authorization header and session cookies of the request
+// in a stable form, or an empty string for anonymous requests
+// Without SessionCookies every cookie is treated as a session cookie
+func (p *privacy) credentials(c *gin.Context) string {
+	var parts []string
+
+	if authorization := c.GetHeader("Authorization"); authorization != "" {
+		parts = append(parts, "authorization="+authorization)
+	}
+
+	var cookies []string
+	for _, cookie := range c.Request.Cookies() {
+		if len(p.sessionCookies) == 0 || slices.Contains(p.sessionCookies, cookie.Name) {
+			cookies = append(cookies, "cookie:"+cookie.Name+"="+cookie.Value)
+		}
+	}
+	sort.Strings(cookies)
+
+	return strings.Join(append(parts, cookies...), "\x00")
+}
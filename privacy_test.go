@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPrivacy_CredentialedRequestsBypass tests that authenticated and cookie-bearing reads are not cached
+func TestPrivacy_CredentialedRequestsBypass(t *testing.T) {
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	router := setupTestRouter(cache, CacheConfig{
+		TTL:            10 * time.Second,
+		SessionCookies: []string{"session"},
+		Sensitive: func(c *gin.Context) bool {
+			return c.Query("preview") != ""
+		},
+	})
+
+	callCount := 0
+	router.GET("/v1/product", func(c *gin.Context) {
+		callCount++
+		c.JSON(http.StatusOK, gin.H{"count": callCount})
+	})
+
+	serve := func(header, value, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	serve("Authorization", "Bearer alice", "/v1/product")
+	serve("Cookie", "session=alice", "/v1/product")
+	serve("", "", "/v1/product?preview=1")
+	assert.Equal(t, 3, callCount, "credentialed and sensitive reads should bypass the cache")
+
+	// The anonymous caller never sees a credentialed response
+	w := serve("", "", "/v1/product")
+	assert.Equal(t, `{"count":4}`, w.Body.String())
+
+	// Cookies other than session cookies don't count as credentials
+	w = serve("Cookie", "theme=dark", "/v1/product")
+	assert.Equal(t, `{"count":4}`, w.Body.String())
+
+	// Cleanup
+	err = cache.DelWildCard(context.Background(), "/v1/*")
+	assert.NoError(t, err)
+}
+
+// TestPrivacy_PerUserKeys tests that opted-in routes are cached per user and invalidated for every user
+func TestPrivacy_PerUserKeys(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+
+	router := setupTestRouter(cache, CacheConfig{
+		TTL:         10 * time.Second,
+		PerUserKeys: []Rule{{Route: "/v1/cart"}},
+	})
+
+	callCount := 0
+	router.GET("/v1/cart", func(c *gin.Context) {
+		callCount++
+		c.JSON(http.StatusOK, gin.H{"owner": c.GetHeader("Authorization")})
+	})
+	router.POST("/v1/cart", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"message": "added"})
+	})
+
+	get := func(user string) string {
+		req := httptest.NewRequest("GET", "/v1/cart", nil)
+		req.Header.Set("Authorization", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	assert.Contains(t, get("Bearer alice"), "alice")
+	assert.Contains(t, get("Bearer bob"), "bob")
+	assert.Contains(t, get("Bearer alice"), "alice", "users should never see each other's entries")
+	assert.Equal(t, 2, callCount)
+
+	// Keys are partitioned by a hash, never the raw credentials
+	keys, err := client.Keys(ctx, "u:*").Result()
+	assert.NoError(t, err)
+	assert.Len(t, keys, 2)
+	for _, key := range keys {
+		assert.True(t, strings.HasSuffix(key, ":/v1/cart"))
+		assert.NotContains(t, key, "alice")
+	}
+
+	// Writes invalidate every user's entries
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/cart", nil))
+	keys, err = client.Keys(ctx, "u:*").Result()
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+// TestPrivacy_SetCookieNotStored tests that responses setting cookies are never stored
+func TestPrivacy_SetCookieNotStored(t *testing.T) {
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	router := setupTestRouter(cache, CacheConfig{TTL: 10 * time.Second})
+
+	callCount := 0
+	router.GET("/v1/welcome", func(c *gin.Context) {
+		callCount++
+		c.SetCookie("session", "new-session", 3600, "/", "", true, true)
+		c.JSON(http.StatusOK, gin.H{"message": "welcome"})
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/welcome", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/welcome", nil))
+	assert.Equal(t, 2, callCount, "response with Set-Cookie should not be cached")
+
+	// Cleanup
+	err = cache.DelWildCard(context.Background(), "/v1/*")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMaxReadBodySize caps the request body of a cacheable read (64 KiB)
+const defaultMaxReadBodySize = 64 << 10
+
+// bodyKeySeparator separates the request key from the body hash
+// The key still starts with the request path, so invalidating the resource deletes it
+const bodyKeySeparator = "#body="
+
+// bodyReads decides which requests with a body are cached like GET requests
+type bodyReads struct {
+	rules   []compiledRule
+	maxSize int64
+}
+
+// newBodyReads compiles the CacheableReads rules of the middleware configuration
+func newBodyReads(config CacheConfig) *bodyReads {
+	reads := &bodyReads{maxSize: config.MaxReadBodySize}
+	if reads.maxSize <= 0 {
+		reads.maxSize = defaultMaxReadBodySize
+	}
+
+	for _, rule := range config.CacheableReads {
+		reads.rules = append(reads.rules, compileRule(rule))
+	}
+
+	return reads
+}
+
+// matches reports whether the request is a cacheable read
+// GET, HEAD and OPTIONS requests are never body reads
+func (r *bodyReads) matches(c *gin.Context, resource string) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+
+	for _, rule := range r.rules {
+		if rule.matches(c, resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// key appends a hash of the request body to key and restores the body for the handler
+// It returns false for bodies larger than the limit or that can't be read
+func (r *bodyReads) key(c *gin.Context, key string) (string, bool) {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return key + bodyKeySeparator + hashBody(nil), true
+	}
+
+	body := c.Request.Body
+	data, err := io.ReadAll(io.LimitReader(body, r.maxSize+1))
+
+	// The handler reads whatever was consumed followed by the rest of the body
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), body), body}
+
+	if err != nil || int64(len(data)) > r.maxSize {
+		return "", false
+	}
+
+	return key + bodyKeySeparator + hashBody(data), true
+}
+
+// hashBody hashes a request body, JSON bodies in canonical form so that key order and
+// whitespace don't matter. Other bodies are hashed as they are.
+func hashBody(data []byte) string {
+	if canonical, ok := canonicalJSON(data); ok {
+		data = canonical
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// canonicalJSON re-encodes a single JSON value with sorted object keys and no whitespace
+// Numbers keep their literal form
+func canonicalJSON(data []byte) ([]byte, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, false
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, false
+	}
+
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	return canonical, true
+}
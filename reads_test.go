@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReads_CachedByBody tests that cacheable reads are keyed by their canonical body and never invalidate
+func TestReads_CachedByBody(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+	cleanup := func() {
+		keys, _ := client.Keys(ctx, `/v1/product/search#body=*`).Result()
+		keys = append(keys, "/v1/product/1")
+		client.Del(ctx, keys...)
+	}
+	cleanup()
+	defer cleanup()
+
+	router := setupTestRouter(cache, CacheConfig{
+		TTL:            10 * time.Second,
+		CacheableReads: []Rule{{Route: "/v1/product/search", Methods: []string{"POST"}}},
+	})
+
+	callCount := 0
+	router.POST("/v1/product/search", func(c *gin.Context) {
+		callCount++
+		body, _ := io.ReadAll(c.Request.Body)
+		c.JSON(http.StatusOK, gin.H{"query": string(body)})
+	})
+	router.GET("/v1/product/1", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": "1"})
+	})
+
+	search := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/v1/product/search", strings.NewReader(body)))
+		return w
+	}
+
+	// The handler still reads the full body
+	w := search(`{"name": "phone", "filters": {"max": 100, "min": 1.50}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `phone`)
+	assert.Equal(t, 1, callCount)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/product/1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Key order and whitespace don't matter, and the search didn't invalidate product
+	w = search(`{"filters":{"min":1.50,"max":100},"name":"phone"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, callCount)
+	assert.Equal(t, int64(1), client.Exists(ctx, "/v1/product/1").Val())
+
+	// Different values are different entries
+	search(`{"filters":{"min":1.5,"max":100},"name":"phone"}`)
+	search(`{"name":"tablet"}`)
+	assert.Equal(t, 3, callCount)
+
+	keys, err := client.Keys(ctx, `/v1/product/search#body=*`).Result()
+	assert.NoError(t, err)
+	assert.Len(t, keys, 3)
+}
+
+// TestReads_OversizedBody tests that bodies above MaxReadBodySize bypass the cache and reach the handler intact
+func TestReads_OversizedBody(t *testing.T) {
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	router := setupTestRouter(cache, CacheConfig{
+		TTL:             10 * time.Second,
+		CacheableReads:  []Rule{{Path: "/v1/graphql"}},
+		MaxReadBodySize: 16,
+	})
+
+	callCount := 0
+	var received string
+	router.POST("/v1/graphql", func(c *gin.Context) {
+		callCount++
+		body, _ := io.ReadAll(c.Request.Body)
+		received = string(body)
+		c.JSON(http.StatusOK, gin.H{"data": nil})
+	})
+
+	query := `{"query":"{ products { id name } }"}`
+	for range 2 {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/v1/graphql", strings.NewReader(query)))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, query, received)
+	}
+	assert.Equal(t, 2, callCount)
+}
+
+// TestReads_CanonicalJSON tests the canonical form of request bodies
+func TestReads_CanonicalJSON(t *testing.T) {
+	assert.Equal(t, hashBody([]byte(`{"b":[1,2],"a":"x"}`)), hashBody([]byte(" {\"a\": \"x\",\n\"b\": [1, 2]} ")))
+	assert.NotEqual(t, hashBody([]byte(`{"a":[1,2]}`)), hashBody([]byte(`{"a":[2,1]}`)))
+	assert.NotEqual(t, hashBody([]byte(`{"a":1}`)), hashBody([]byte(`{"a":1.0}`)))
+
+	// Trailing data isn't JSON and is hashed as is
+	_, ok := canonicalJSON([]byte(`{"a":1} {"b":2}`))
+	assert.False(t, ok)
+	assert.NotEqual(t, hashBody([]byte("a=1&b=2")), hashBody([]byte("b=2&a=1")))
+}
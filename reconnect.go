@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultReconnectInterval is the delay between reconnect attempts in lazy mode
+const defaultReconnectInterval = 5 * time.Second
+
+// errDegraded is returned by every operation while a lazy cache is reconnecting
+var errDegraded = fmt.Errorf("%w: degraded mode, reconnecting", ErrBackendUnavailable)
+
+// Available reports whether Redis is currently reachable
+// It is always true unless the cache was created with RedisConfig.Lazy
+func (r *redisCache) Available() bool {
+	return !r.degraded.Load()
+}
+
+// ready fails fast while the cache is degraded so requests don't wait on dial timeouts
+func (r *redisCache) ready() error {
+	if r.degraded.Load() {
+		return errDegraded
+	}
+	return nil
+}
+
+// backendError translates go-redis errors like the package-level backendError and,
+// in lazy mode, switches to degraded mode when the connection itself failed
+func (r *redisCache) backendError(err error) error {
+	if r.lazy && isConnectionError(err) {
+		r.degrade()
+	}
+	return backendError(err)
+}
+
+// degrade marks the cache unavailable and starts a single reconnect loop
+func (r *redisCache) degrade() {
+	if !r.degraded.CompareAndSwap(false, true) {
+		return
+	}
+
+	// Rejected only when shutting down, in which case there is nothing to reconnect for
+	r.async(r.reconnect)
+}
+
+// reconnect pings Redis until it answers or the cache is closed
+func (r *redisCache) reconnect() {
+	ticker := time.NewTicker(r.reconnectInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), r.reconnectInterval)
+			err := r.client.Ping(ctx).Err()
+			cancel()
+
+			if err == nil {
+				r.degraded.Store(false)
+				return
+			}
+		}
+	}
+}
+
+// isConnectionError reports whether err means Redis could not be reached,
+// as opposed to a missing key, an error reply or a cancelled request
+func isConnectionError(err error) bool {
+	if err == nil || errors.Is(err, redis.Nil) {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var reply redis.Error
+	if errors.As(err, &reply) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// startProxy forwards connections on addr to the local Redis server until the listener is closed
+func startProxy(t *testing.T, addr string) net.Listener {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to start proxy: %v", err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			upstream, err := net.Dial("tcp", "localhost:6379")
+			if err != nil {
+				conn.Close()
+				continue
+			}
+
+			go func() {
+				defer conn.Close()
+				defer upstream.Close()
+				go io.Copy(upstream, conn)
+				io.Copy(conn, upstream)
+			}()
+		}
+	}()
+
+	return listener
+}
+
+// freeAddr returns a local address nothing is listening on
+func freeAddr(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	return addr
+}
+
+// TestLazy_StartsDegradedAndReconnects tests that a lazy cache starts without Redis
+// and restores caching once Redis becomes reachable
+func TestLazy_StartsDegradedAndReconnects(t *testing.T) {
+	addr := freeAddr(t)
+
+	cfg := RedisConfig{
+		Addr:              addr,
+		DialTimeout:       100 * time.Millisecond,
+		MaxRetries:        -1,
+		Lazy:              true,
+		ReconnectInterval: 50 * time.Millisecond,
+	}
+
+	// Without Lazy the constructor fails
+	_, err := NewRedisCache(RedisConfig{Addr: addr, DialTimeout: 100 * time.Millisecond, MaxRetries: -1})
+	assert.ErrorIs(t, err, ErrBackendUnavailable)
+
+	// With Lazy the cache is created in a degraded state
+	cache, err := NewRedisCache(cfg)
+	assert.NoError(t, err, "lazy cache should start while redis is down")
+	defer cache.(Closer).Close()
+
+	availability, ok := cache.(Availability)
+	assert.True(t, ok, "redis cache should implement Availability")
+	assert.False(t, availability.Available(), "cache should start degraded")
+
+	var wanted string
+	err = cache.Get(context.Background(), "test_key_lazy", &wanted)
+	assert.ErrorIs(t, err, ErrBackendUnavailable, "degraded cache should fail fast")
+
+	// The middleware bypasses the degraded cache without logging errors
+	var logged []string
+	config := CacheConfig{
+		TTL: 10 * time.Second,
+		Logger: func(message string, args ...interface{}) {
+			logged = append(logged, message)
+		},
+	}
+
+	router := setupTestRouter(cache, config)
+
+	callCount := 0
+	router.GET("/v1/product/:id", func(c *gin.Context) {
+		callCount++
+		c.JSON(http.StatusOK, gin.H{"message": "product"})
+	})
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/product/lazy", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	assert.Equal(t, 2, callCount, "degraded cache should be bypassed")
+	assert.Empty(t, logged, "bypass should not log errors")
+
+	// Bring "redis" up and wait for the reconnect loop
+	proxy := startProxy(t, addr)
+	defer proxy.Close()
+
+	assert.Eventually(t, availability.Available, 2*time.Second, 20*time.Millisecond, "cache should reconnect")
+
+	// Caching works again
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/product/lazy", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/product/lazy", nil))
+	assert.Equal(t, 3, callCount, "second request after reconnect should be served from cache")
+
+	// Cleanup
+	err = cache.DelWildCard(context.Background(), "/v1/*")
+	assert.NoError(t, err)
+}
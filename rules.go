@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rule matches requests for CacheConfig.Include and CacheConfig.Exclude
+// Every field that is set must match; a zero Rule matches every request
+type Rule struct {
+	// Path is a glob matched against the request path, e.g. "/v1/product/*/reviews" or "/v1/admin/**"
+	// "*" matches within one path segment and "**" across segments
+	Path string
+
+	// Route is a glob matched against the gin route pattern, e.g. "/v1/user/:id"
+	Route string
+
+	// PathRegexp is a regular expression matched against the request path
+	PathRegexp *regexp.Regexp
+
+	// Resource matches the resolved resource exactly, e.g. "auth"
+	Resource string
+
+	// Methods limits the rule to these HTTP methods
+	Methods []string
+
+	// Headers maps header names to globs the header value must match, where "*" matches
+	// any characters. An empty glob only requires the header to be present.
+	Headers map[string]string
+
+	// Match is an optional predicate for anything the other fields can't express
+	Match func(c *gin.Context) bool
+}
+
+// compiledRule is a Rule with its globs compiled
+type compiledRule struct {
+	rule    Rule
+	path    *regexp.Regexp
+	route   *regexp.Regexp
+	headers map[string]*regexp.Regexp
+}
+
+// ruleSet decides which requests the middleware handles
+type ruleSet struct {
+	include []compiledRule
+	exclude []compiledRule
+}
+
+// newRuleSet compiles the Include and Exclude rules of the middleware configuration
+// Every Outdoors entry becomes an Exclude rule for its resource
+func newRuleSet(config CacheConfig) *ruleSet {
+	rules := &ruleSet{}
+
+	for _, rule := range config.Include {
+		rules.include = append(rules.include, compileRule(rule))
+	}
+
+	for _, rule := range config.Exclude {
+		rules.exclude = append(rules.exclude, compileRule(rule))
+	}
+
+	for _, resource := range config.Outdoors {
+		rules.exclude = append(rules.exclude, compileRule(Rule{Resource: resource}))
+	}
+
+	return rules
+}
+
+// handles reports whether the middleware caches and invalidates for the request
+// Without Include rules every request is included; Exclude rules always win
+func (s *ruleSet) handles(c *gin.Context, resource string) bool {
+	included := len(s.include) == 0
+	for _, rule := range s.include {
+		if rule.matches(c, resource) {
+			included = true
+			break
+		}
+	}
+
+	if !included {
+		return false
+	}
+
+	for _, rule := range s.exclude {
+		if rule.matches(c, resource) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// compileRule compiles the globs of a rule
+func compileRule(rule Rule) compiledRule {
+	compiled := compiledRule{rule: rule}
+
+	if rule.Path != "" {
+		compiled.path = globRegexp(rule.Path, "[^/]*")
+	}
+
+	if rule.Route != "" {
+		compiled.route = globRegexp(rule.Route, "[^/]*")
+	}
+
+	if len(rule.Headers) > 0 {
+		compiled.headers = make(map[string]*regexp.Regexp, len(rule.Headers))
+		for name, value := range rule.Headers {
+			compiled.headers[name] = nil
+			if value != "" {
+				compiled.headers[name] = globRegexp(value, ".*")
+			}
+		}
+	}
+
+	return compiled
+}
+
+// matches reports whether every field set on the rule matches the request
+func (r compiledRule) matches(c *gin.Context, resource string) bool {
+	path := c.Request.URL.Path
+
+	if len(r.rule.Methods) > 0 && !containsFold(r.rule.Methods, c.Request.Method) {
+		return false
+	}
+
+	if r.rule.Resource != "" && r.rule.Resource != resource {
+		return false
+	}
+
+	if r.path != nil && !r.path.MatchString(path) {
+		return false
+	}
+
+	if r.route != nil && !r.route.MatchString(c.FullPath()) {
+		return false
+	}
+
+	if r.rule.PathRegexp != nil && !r.rule.PathRegexp.MatchString(path) {
+		return false
+	}
+
+	for name, value := range r.headers {
+		values := c.Request.Header.Values(name)
+		if len(values) == 0 {
+			return false
+		}
+		if value != nil && !value.MatchString(strings.Join(values, ", ")) {
+			return false
+		}
+	}
+
+	if r.rule.Match != nil && !r.rule.Match(c) {
+		return false
+	}
+
+	return true
+}
+
+// globRegexp compiles a glob into an anchored regular expression
+// "**" matches any characters and "*" matches star, e.g. "[^/]*" for a single path segment
+func globRegexp(glob, star string) *regexp.Regexp {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			pattern.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			pattern.WriteString(star)
+		default:
+			pattern.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+
+	pattern.WriteString("$")
+	return regexp.MustCompile(pattern.String())
+}
+
+// containsFold reports whether methods contains method, ignoring case
+func containsFold(methods []string, method string) bool {
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRuleSet_Handles tests matching of globs, routes, regexes, methods and headers
+func TestRuleSet_Handles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	rules := newRuleSet(CacheConfig{
+		Exclude: []Rule{
+			{Path: "/v1/product/*/reviews"},
+			{Path: "/v1/admin/**"},
+			{Route: "/v1/user/me"},
+			{PathRegexp: regexp.MustCompile(`^/v1/report/\d+$`)},
+			{Resource: "order", Methods: []string{"GET"}},
+			{Headers: map[string]string{"X-Debug": ""}},
+			{Headers: map[string]string{"Accept": "text/event-stream*"}},
+		},
+		Outdoors: []string{"health"},
+	})
+
+	var handled bool
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		handled = rules.handles(c, getBaseURL(c.Request.URL.Path))
+	})
+	for _, route := range []string{"/v1/product/:id", "/v1/product/:id/reviews", "/v1/admin/*any", "/v1/user/me", "/v1/user/:id", "/v1/report/:id", "/v1/order", "/v1/health"} {
+		router.Any(route, func(c *gin.Context) {})
+	}
+
+	cases := []struct {
+		method  string
+		path    string
+		header  http.Header
+		handled bool
+	}{
+		{"GET", "/v1/product/1", nil, true},
+		{"GET", "/v1/product/1/reviews", nil, false},
+		{"GET", "/v1/admin/users/1/roles", nil, false},
+		{"GET", "/v1/user/me", nil, false},
+		{"GET", "/v1/user/1", nil, true},
+		{"GET", "/v1/report/42", nil, false},
+		{"GET", "/v1/report/latest", nil, true},
+		{"GET", "/v1/order", nil, false},
+		{"POST", "/v1/order", nil, true},
+		{"GET", "/v1/health", nil, false},
+		{"GET", "/v1/product/1", http.Header{"X-Debug": {"1"}}, false},
+		{"GET", "/v1/product/1", http.Header{"Accept": {"text/event-stream; charset=utf-8"}}, false},
+		{"GET", "/v1/product/1", http.Header{"Accept": {"application/json"}}, true},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		for name, values := range tc.header {
+			req.Header[name] = values
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, tc.handled, handled, "%s %s %v", tc.method, tc.path, tc.header)
+	}
+}
+
+// TestRuleSet_Include tests that Include limits the middleware to matching requests
+func TestRuleSet_Include(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	rules := newRuleSet(CacheConfig{
+		Include: []Rule{{Path: "/v1/catalog/**"}},
+		Exclude: []Rule{{Path: "/v1/catalog/draft/**"}},
+	})
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	c.Request = httptest.NewRequest("GET", "/v1/catalog/shoes", nil)
+	assert.True(t, rules.handles(c, "catalog"))
+
+	c.Request = httptest.NewRequest("GET", "/v1/catalog/draft/shoes", nil)
+	assert.False(t, rules.handles(c, "catalog"), "exclude should win over include")
+
+	c.Request = httptest.NewRequest("GET", "/v1/product", nil)
+	assert.False(t, rules.handles(c, "product"))
+}
+
+// TestMiddleware_ExcludeReadsOnly tests that excluding GET keeps invalidation on writes
+func TestMiddleware_ExcludeReadsOnly(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	router := setupTestRouter(cache, CacheConfig{
+		TTL: 10 * time.Second,
+		Groups: map[string][]string{
+			"order": {"product"},
+		},
+		Exclude: []Rule{{Resource: "order", Methods: []string{"GET"}}},
+	})
+
+	orderCallCount := 0
+	router.GET("/v1/order", func(c *gin.Context) {
+		orderCallCount++
+		c.JSON(http.StatusOK, gin.H{"message": "orders"})
+	})
+	router.POST("/v1/order", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"message": "created"})
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/order", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/order", nil))
+	assert.Equal(t, 2, orderCallCount, "excluded GET should not be cached")
+
+	assert.NoError(t, cache.Set(ctx, "/v1/product", []byte(`{}`), 10*time.Second))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/order", nil))
+
+	var body []byte
+	err = cache.Get(ctx, "/v1/product", &body)
+	assert.ErrorIs(t, err, ErrCacheMiss, "writes should still invalidate the group")
+
+	// Cleanup
+	assert.NoError(t, cache.DelWildCard(ctx, "/v1/*"))
+}
@@ -0,0 +1,291 @@
+package cache
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SecureMode selects how a secure cache protects stored values
+type SecureMode byte
+
+const (
+	// SecureEncrypt encrypts and authenticates values with AES-GCM
+	SecureEncrypt SecureMode = iota + 1
+
+	// SecureSign authenticates values with HMAC-SHA256, leaving them readable
+	SecureSign
+)
+
+// sealedMagic starts every entry written by a secure cache
+// It is followed by the SecureMode byte, the key ID length, the key ID and the sealed body
+const sealedMagic = "\x00s"
+
+// minSigningKeySize is the smallest HMAC key accepted, matching the SHA-256 output size
+const minSigningKeySize = 32
+
+// Reasons reported to Metrics when an entry is rejected
+const (
+	integrityUnsealed   = "unsealed"
+	integrityWrongMode  = "wrong_mode"
+	integrityUnknownKey = "unknown_key"
+	integrityTampered   = "tampered"
+)
+
+// SecureConfig holds the configuration of a secure cache
+type SecureConfig struct {
+	// Mode is SecureEncrypt (default) or SecureSign
+	Mode SecureMode
+
+	// Keys maps key IDs to keys. AES keys must be 16, 24 or 32 bytes, HMAC keys at least 32 bytes.
+	// Keep retired keys here until entries written with them have expired.
+	Keys map[string][]byte
+
+	// ActiveKeyID selects the key used for new entries
+	ActiveKeyID string
+
+	// Codec serializes non-[]byte values before they are sealed (default JSONCodec)
+	Codec Codec
+
+	// Metrics is an optional collector that counts rejected entries
+	Metrics *Metrics
+}
+
+// secureCache wraps a Cache and seals every value before it reaches the inner cache
+type secureCache struct {
+	inner    Cache
+	mode     SecureMode
+	activeID string
+	aeads    map[string]cipher.AEAD
+	macKeys  map[string][]byte
+	codec    Codec
+	metrics  *Metrics
+}
+
+// NewSecureCache wraps inner so that values are encrypted (SecureEncrypt) or signed (SecureSign)
+// Entries are bound to their cache key, so a valid entry copied to another key is rejected too.
+// Get returns ErrIntegrity for entries that fail verification instead of serving them.
+func NewSecureCache(inner Cache, cfg SecureConfig) (Cache, error) {
+	if cfg.Mode == 0 {
+		cfg.Mode = SecureEncrypt
+	}
+
+	if cfg.Codec == nil {
+		cfg.Codec = JSONCodec
+	}
+
+	if _, ok := cfg.Keys[cfg.ActiveKeyID]; !ok {
+		return nil, fmt.Errorf("secure cache: active key %q not found in keys", cfg.ActiveKeyID)
+	}
+
+	for id := range cfg.Keys {
+		if len(id) > 255 {
+			return nil, fmt.Errorf("secure cache: key ID %q must be at most 255 bytes", id)
+		}
+	}
+
+	s := &secureCache{
+		inner:    inner,
+		mode:     cfg.Mode,
+		activeID: cfg.ActiveKeyID,
+		codec:    cfg.Codec,
+		metrics:  cfg.Metrics,
+	}
+
+	switch cfg.Mode {
+	case SecureEncrypt:
+		s.aeads = make(map[string]cipher.AEAD, len(cfg.Keys))
+		for id, key := range cfg.Keys {
+			block, err := aes.NewCipher(key)
+			if err != nil {
+				return nil, fmt.Errorf("secure cache: key %q: %w", id, err)
+			}
+			aead, err := cipher.NewGCM(block)
+			if err != nil {
+				return nil, fmt.Errorf("secure cache: key %q: %w", id, err)
+			}
+			s.aeads[id] = aead
+		}
+
+	case SecureSign:
+		s.macKeys = make(map[string][]byte, len(cfg.Keys))
+		for id, key := range cfg.Keys {
+			if len(key) < minSigningKeySize {
+				return nil, fmt.Errorf("secure cache: key %q must be at least %d bytes", id, minSigningKeySize)
+			}
+			s.macKeys[id] = key
+		}
+
+	default:
+		return nil, fmt.Errorf("secure cache: unknown mode %d", cfg.Mode)
+	}
+
+	return s, nil
+}
+
+// Set seals the encoded value with the active key and stores it in the inner cache
+func (s *secureCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	id, payload, err := encodeValue(s.codec, value)
+	if err != nil {
+		return err
+	}
+
+	sealed, err := s.seal(key, frameEntry(id, payload))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrEncode, err)
+	}
+
+	return s.inner.Set(ctx, key, sealed, ttl)
+}
+
+// Get loads the entry from the inner cache, verifies it and decodes it into wanted
+func (s *secureCache) Get(ctx context.Context, key string, wanted interface{}) error {
+	var sealed []byte
+	if err := s.inner.Get(ctx, key, &sealed); err != nil {
+		return err
+	}
+
+	plaintext, reason, err := s.open(key, sealed)
+	if err != nil {
+		s.metrics.integrityFailure(reason)
+		return fmt.Errorf("%w: %s: %w", ErrIntegrity, reason, err)
+	}
+
+	return decodeEntry(plaintext, wanted, s.codec)
+}
+
+// Del deletes keys from the inner cache
+func (s *secureCache) Del(ctx context.Context, keys ...string) error {
+	return s.inner.Del(ctx, keys...)
+}
+
+// DelCount forwards to the inner cache when it can count deleted keys
+func (s *secureCache) DelCount(ctx context.Context, keys ...string) (int64, error) {
+	return delCount(ctx, s.inner, keys...)
+}
+
+// DelWildCard deletes keys matching the wildcard pattern from the inner cache
+func (s *secureCache) DelWildCard(ctx context.Context, wildcard string) error {
+	return s.inner.DelWildCard(ctx, wildcard)
+}
+
+// DelWildCardCount forwards to the inner cache when it can count deleted keys
+func (s *secureCache) DelWildCardCount(ctx context.Context, wildcard string) (int64, error) {
+	return delWildCard(ctx, s.inner, wildcard)
+}
+
+// Available forwards to the inner cache
+func (s *secureCache) Available() bool {
+	return available(s.inner)
+}
+
+// Ping forwards to the inner cache when it implements Pinger
+func (s *secureCache) Ping(ctx context.Context) error {
+	if p, ok := s.inner.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// Close forwards to the inner cache when it implements Closer
+func (s *secureCache) Close() error {
+	if c, ok := s.inner.(Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Shutdown forwards to the inner cache when it implements Shutdowner
+func (s *secureCache) Shutdown(ctx context.Context) error {
+	if sd, ok := s.inner.(Shutdowner); ok {
+		return sd.Shutdown(ctx)
+	}
+	return nil
+}
+
+// seal encrypts or signs plaintext with the active key, bound to the cache key
+func (s *secureCache) seal(key string, plaintext []byte) ([]byte, error) {
+	header := make([]byte, 0, len(sealedMagic)+2+len(s.activeID))
+	header = append(header, sealedMagic...)
+	header = append(header, byte(s.mode), byte(len(s.activeID)))
+	header = append(header, s.activeID...)
+
+	if s.mode == SecureEncrypt {
+		aead := s.aeads[s.activeID]
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+
+		sealed := append(header, nonce...)
+		return aead.Seal(sealed, nonce, plaintext, []byte(key)), nil
+	}
+
+	sealed := append(header, signEntry(s.macKeys[s.activeID], key, plaintext)...)
+	return append(sealed, plaintext...), nil
+}
+
+// open verifies and decrypts a sealed entry
+// On failure it returns the reason reported to metrics
+func (s *secureCache) open(key string, sealed []byte) ([]byte, string, error) {
+	if !hasMagic(sealed, sealedMagic, 2) {
+		return nil, integrityUnsealed, errors.New("entry is not sealed")
+	}
+
+	mode := SecureMode(sealed[len(sealedMagic)])
+	if mode != s.mode {
+		return nil, integrityWrongMode, fmt.Errorf("entry sealed with mode %d", mode)
+	}
+
+	idLen := int(sealed[len(sealedMagic)+1])
+	body := sealed[len(sealedMagic)+2:]
+	if len(body) < idLen {
+		return nil, integrityTampered, errors.New("truncated entry")
+	}
+	keyID, body := string(body[:idLen]), body[idLen:]
+
+	if mode == SecureEncrypt {
+		aead, ok := s.aeads[keyID]
+		if !ok {
+			return nil, integrityUnknownKey, fmt.Errorf("unknown key %q", keyID)
+		}
+		if len(body) < aead.NonceSize() {
+			return nil, integrityTampered, errors.New("truncated entry")
+		}
+
+		plaintext, err := aead.Open(nil, body[:aead.NonceSize()], body[aead.NonceSize():], []byte(key))
+		if err != nil {
+			return nil, integrityTampered, err
+		}
+		return plaintext, "", nil
+	}
+
+	macKey, ok := s.macKeys[keyID]
+	if !ok {
+		return nil, integrityUnknownKey, fmt.Errorf("unknown key %q", keyID)
+	}
+	if len(body) < sha256.Size {
+		return nil, integrityTampered, errors.New("truncated entry")
+	}
+
+	mac, plaintext := body[:sha256.Size], body[sha256.Size:]
+	if !hmac.Equal(mac, signEntry(macKey, key, plaintext)) {
+		return nil, integrityTampered, errors.New("signature mismatch")
+	}
+
+	return plaintext, "", nil
+}
+
+// signEntry computes the HMAC of plaintext bound to the cache key
+func signEntry(macKey []byte, key string, plaintext []byte) []byte {
+	mac := hmac.New(sha256.New, macKey)
+	fmt.Fprintf(mac, "%d:%s", len(key), key)
+	mac.Write(plaintext)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testKeyOld = bytes.Repeat([]byte{0x01}, 32)
+	testKeyNew = bytes.Repeat([]byte{0x02}, 32)
+)
+
+// TestSecure_EncryptAndRotate tests AES-GCM encryption and key rotation
+func TestSecure_EncryptAndRotate(t *testing.T) {
+	ctx := context.Background()
+
+	inner, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+
+	oldCache, err := NewSecureCache(inner, SecureConfig{
+		Keys:        map[string][]byte{"k1": testKeyOld},
+		ActiveKeyID: "k1",
+	})
+	assert.NoError(t, err)
+
+	expected := TestObject{ID: "1", Name: "Alice", Age: 25}
+	err = oldCache.Set(ctx, "test_secure_user", expected, 10*time.Second)
+	assert.NoError(t, err)
+
+	// Plaintext never reaches Redis
+	stored, err := client.Get(ctx, "test_secure_user").Bytes()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(stored), "Alice", "value should be encrypted")
+
+	// After rotation, entries sealed with the retired key are still readable
+	rotated, err := NewSecureCache(inner, SecureConfig{
+		Keys:        map[string][]byte{"k1": testKeyOld, "k2": testKeyNew},
+		ActiveKeyID: "k2",
+	})
+	assert.NoError(t, err)
+
+	var actual TestObject
+	err = rotated.Get(ctx, "test_secure_user", &actual)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	// New entries use the active key and can't be read once it's unknown
+	err = rotated.Set(ctx, "test_secure_user", expected, 10*time.Second)
+	assert.NoError(t, err)
+
+	err = oldCache.Get(ctx, "test_secure_user", &actual)
+	assert.ErrorIs(t, err, ErrIntegrity, "entry sealed with an unknown key should be rejected")
+
+	assert.NoError(t, inner.Del(ctx, "test_secure_user"))
+}
+
+// TestSecure_RejectsTamperedEntries tests that tampered, unsealed and moved entries are rejected and counted
+func TestSecure_RejectsTamperedEntries(t *testing.T) {
+	ctx := context.Background()
+	metrics := NewMetrics(MetricsConfig{})
+
+	inner, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+
+	for _, mode := range []SecureMode{SecureEncrypt, SecureSign} {
+		secure, err := NewSecureCache(inner, SecureConfig{
+			Mode:        mode,
+			Keys:        map[string][]byte{"k1": testKeyOld},
+			ActiveKeyID: "k1",
+			Metrics:     metrics,
+		})
+		assert.NoError(t, err)
+
+		err = secure.Set(ctx, "test_secure_tamper", "balance=100", 10*time.Second)
+		assert.NoError(t, err)
+
+		var value string
+		err = secure.Get(ctx, "test_secure_tamper", &value)
+		assert.NoError(t, err)
+		assert.Equal(t, "balance=100", value)
+
+		// Flip the last byte of the stored entry
+		stored, err := client.Get(ctx, "test_secure_tamper").Bytes()
+		assert.NoError(t, err)
+		stored[len(stored)-1] ^= 0xff
+		assert.NoError(t, client.Set(ctx, "test_secure_tamper", stored, 10*time.Second).Err())
+
+		err = secure.Get(ctx, "test_secure_tamper", &value)
+		assert.ErrorIs(t, err, ErrIntegrity, "tampered entry should be rejected")
+
+		// A valid entry copied to another key is rejected
+		err = secure.Set(ctx, "test_secure_tamper", "balance=100", 10*time.Second)
+		assert.NoError(t, err)
+		stored, err = client.Get(ctx, "test_secure_tamper").Bytes()
+		assert.NoError(t, err)
+		assert.NoError(t, client.Set(ctx, "test_secure_moved", stored, 10*time.Second).Err())
+
+		err = secure.Get(ctx, "test_secure_moved", &value)
+		assert.ErrorIs(t, err, ErrIntegrity, "entry moved to another key should be rejected")
+
+		// Entry written without protection is rejected
+		err = inner.Set(ctx, "test_secure_tamper", "balance=1000000", 10*time.Second)
+		assert.NoError(t, err)
+
+		err = secure.Get(ctx, "test_secure_tamper", &value)
+		assert.ErrorIs(t, err, ErrIntegrity, "unsealed entry should be rejected")
+
+		// Misses are still misses
+		err = secure.Get(ctx, "test_secure_missing", &value)
+		assert.ErrorIs(t, err, ErrCacheMiss)
+	}
+
+	assert.Equal(t, 4.0, testutil.ToFloat64(metrics.integrity.WithLabelValues(integrityTampered)))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.integrity.WithLabelValues(integrityUnsealed)))
+
+	assert.NoError(t, inner.Del(ctx, "test_secure_tamper", "test_secure_moved"))
+}
+
+// TestSecure_Config tests configuration validation
+func TestSecure_Config(t *testing.T) {
+	inner, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	_, err = NewSecureCache(inner, SecureConfig{Keys: map[string][]byte{"k1": testKeyOld}, ActiveKeyID: "k2"})
+	assert.Error(t, err, "missing active key should be rejected")
+
+	_, err = NewSecureCache(inner, SecureConfig{Keys: map[string][]byte{"k1": []byte("short")}, ActiveKeyID: "k1"})
+	assert.Error(t, err, "invalid AES key size should be rejected")
+
+	_, err = NewSecureCache(inner, SecureConfig{Mode: SecureSign, Keys: map[string][]byte{"k1": []byte("short")}, ActiveKeyID: "k1"})
+	assert.Error(t, err, "short HMAC key should be rejected")
+}
+
+// TestSecure_Middleware tests that the middleware works on top of a secure cache
+func TestSecure_Middleware(t *testing.T) {
+	inner, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	secure, err := NewSecureCache(inner, SecureConfig{
+		Keys:        map[string][]byte{"k1": testKeyOld},
+		ActiveKeyID: "k1",
+	})
+	assert.NoError(t, err)
+
+	router := setupTestRouter(secure, CacheConfig{TTL: 10 * time.Second})
+
+	callCount := 0
+	router.GET("/v1/user/:id", func(c *gin.Context) {
+		callCount++
+		c.JSON(http.StatusOK, gin.H{"email": "alice@example.com"})
+	})
+
+	w1 := httptest.NewRecorder()
+	router.ServeHTTP(w1, httptest.NewRequest("GET", "/v1/user/1", nil))
+	w2 := httptest.NewRecorder()
+	router.ServeHTTP(w2, httptest.NewRequest("GET", "/v1/user/1", nil))
+
+	assert.Equal(t, 1, callCount, "second request should be served from cache")
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+
+	// Cleanup
+	err = secure.DelWildCard(context.Background(), "/v1/*")
+	assert.NoError(t, err)
+}
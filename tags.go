@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tagKeyPrefix prefixes the Redis set that indexes the keys carrying a tag
+// It never matches the "/v1/" invalidation patterns, so wildcard deletes leave tag sets alone
+const tagKeyPrefix = "tag:"
+
+// Tagger is implemented by caches that can index keys by tag
+// The middleware uses it for AddTags and the Invalidator for InvalidateTags
+type Tagger interface {
+	// Tag adds key to every tag; tags live at least as long as ttl
+	Tag(ctx context.Context, key string, ttl time.Duration, tags ...string) error
+
+	// InvalidateTags deletes every key carrying one of the tags and returns how many existed
+	InvalidateTags(ctx context.Context, tags ...string) (int64, error)
+}
+
+// Tag adds key to the Redis set of every tag
+// Each set expires with its longest-lived member, so a short TTL never orphans longer entries
+func (r *redisCache) Tag(ctx context.Context, key string, ttl time.Duration, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	if err := r.ready(); err != nil {
+		return err
+	}
+
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tag := range tags {
+			tagKey := tagKeyPrefix + tag
+			pipe.SAdd(ctx, tagKey, key)
+			if ttl > 0 {
+				// NX sets the expiry of a new set, GT only ever extends it
+				pipe.ExpireNX(ctx, tagKey, ttl)
+				pipe.ExpireGT(ctx, tagKey, ttl)
+			}
+		}
+		return nil
+	})
+	return r.backendError(err)
+}
+
+// InvalidateTags deletes every key carrying one of the tags together with the tag sets
+func (r *redisCache) InvalidateTags(ctx context.Context, tags ...string) (int64, error) {
+	if len(tags) == 0 {
+		return 0, nil
+	}
+
+	if err := r.ready(); err != nil {
+		return 0, err
+	}
+
+	var keys []string
+	for _, tag := range tags {
+		members, err := r.client.SMembers(ctx, tagKeyPrefix+tag).Result()
+		if err != nil {
+			return 0, r.backendError(err)
+		}
+		keys = append(keys, members...)
+	}
+
+	deleted, err := r.delKeys(ctx, keys)
+	if err != nil {
+		return deleted, err
+	}
+
+	tagKeys := make([]string, len(tags))
+	for i, tag := range tags {
+		tagKeys[i] = tagKeyPrefix + tag
+	}
+
+	_, err = r.delKeys(ctx, tagKeys)
+	return deleted, err
+}
+
+// Tag forwards to the inner cache when it implements Tagger
+func (s *secureCache) Tag(ctx context.Context, key string, ttl time.Duration, tags ...string) error {
+	return tag(ctx, s.inner, key, ttl, tags...)
+}
+
+// InvalidateTags forwards to the inner cache when it implements Tagger
+func (s *secureCache) InvalidateTags(ctx context.Context, tags ...string) (int64, error) {
+	return invalidateTags(ctx, s.inner, tags...)
+}
+
+// InvalidateTags deletes every cached response tagged with one of the tags, e.g. with AddTags
+// The cache must implement Tagger
+func (inv *Invalidator) InvalidateTags(ctx context.Context, tags ...string) (InvalidationReport, error) {
+	return inv.run(ctx, nil, time.Now(), tagTargets("invalidator.invalidateTags", tags))
+}
+
+// tagTargets invalidates every tag
+func tagTargets(op string, tags []string) []invalidationTarget {
+	targets := make([]invalidationTarget, len(tags))
+	for i, tag := range tags {
+		targets[i] = invalidationTarget{op: op, pattern: tag, tag: true}
+	}
+	return targets
+}
+
+// errTagsUnsupported is returned for tag operations on caches that don't implement Tagger
+var errTagsUnsupported = fmt.Errorf("cache: tags: %w", errors.ErrUnsupported)
+
+// tag tags key on any Cache
+func tag(ctx context.Context, cache Cache, key string, ttl time.Duration, tags ...string) error {
+	if tagger, ok := cache.(Tagger); ok {
+		return tagger.Tag(ctx, key, ttl, tags...)
+	}
+	return errTagsUnsupported
+}
+
+// invalidateTags deletes tagged keys on any Cache
+func invalidateTags(ctx context.Context, cache Cache, tags ...string) (int64, error) {
+	if tagger, ok := cache.(Tagger); ok {
+		return tagger.InvalidateTags(ctx, tags...)
+	}
+	return 0, errTagsUnsupported
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation scope reported on every span
+const tracerName = "github.com/xasannosir/gin-redis-cache"
+
+// Span names used by the middleware
+const (
+	spanLookup     = "cache.lookup"
+	spanStore      = "cache.store"
+	spanInvalidate = "cache.invalidate"
+)
+
+// Span attribute keys used by the middleware
+const (
+	attrKeyHash     = attribute.Key("cache.key_hash")
+	attrOutcome     = attribute.Key("cache.outcome")
+	attrResource    = attribute.Key("cache.resource")
+	attrPattern     = attribute.Key("cache.pattern")
+	attrGroup       = attribute.Key("cache.group")
+	attrGroupFanout = attribute.Key("cache.group.fanout")
+	attrKeysDeleted = attribute.Key("cache.keys_deleted")
+	attrBodySize    = attribute.Key("cache.body_size")
+)
+
+// newTracer returns the tracer for the configured provider
+// Without a provider spans are created by a no-op tracer and cost next to nothing
+func newTracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = noop.NewTracerProvider()
+	}
+	return tp.Tracer(tracerName)
+}
+
+// startSpan starts a client span as a child of the incoming request span
+func startSpan(ctx context.Context, tracer trace.Tracer, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endSpan records the outcome and error, if any, and ends the span
+func endSpan(span trace.Span, outcome Outcome, err error) {
+	span.SetAttributes(attrOutcome.String(string(outcome)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// hashKey returns a short, stable hash of a cache key
+// Keys contain query strings that may carry personal data, so spans never see them raw
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
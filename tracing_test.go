@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spanAttributes collects span attributes into a map for assertions
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+// TestTracing_Spans tests that lookups, stores and invalidations create child spans of the request span
+func TestTracing_Spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	cfg := RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Password: "",
+		Database: 0,
+	}
+	cache, err := NewRedisCache(cfg)
+	assert.NoError(t, err)
+
+	config := CacheConfig{
+		TTL: 10 * time.Second,
+		Groups: map[string][]string{
+			"product": {"category", "inventory"},
+		},
+		TracerProvider: provider,
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	// Simulate an upstream tracing middleware that starts the request span
+	router.Use(func(c *gin.Context) {
+		ctx, span := provider.Tracer("test").Start(c.Request.Context(), "request")
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	})
+	router.Use(SetOrGetCache(cache, config))
+
+	router.GET("/v1/product/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "product"})
+	})
+	router.DELETE("/v1/product/:id", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/product/42", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/product/42", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/v1/product/42", nil))
+
+	spans := recorder.Ended()
+	byName := make(map[string][]sdktrace.ReadOnlySpan)
+	parents := make(map[string]bool)
+	for _, span := range spans {
+		byName[span.Name()] = append(byName[span.Name()], span)
+		if span.Name() == "request" {
+			parents[span.SpanContext().SpanID().String()] = true
+		}
+	}
+
+	// Every cache span should be a child of a request span
+	for _, span := range spans {
+		if span.Name() != "request" {
+			assert.True(t, parents[span.Parent().SpanID().String()], "%s should be a child of the request span", span.Name())
+		}
+	}
+
+	// Lookup spans: miss then hit, key is hashed
+	if assert.Len(t, byName[spanLookup], 2) {
+		first := spanAttributes(byName[spanLookup][0])
+		assert.Equal(t, "miss", first[attrOutcome].AsString())
+		assert.Equal(t, hashKey("/v1/product/42"), first[attrKeyHash].AsString())
+		assert.Equal(t, "product", first[attrResource].AsString())
+
+		second := spanAttributes(byName[spanLookup][1])
+		assert.Equal(t, "hit", second[attrOutcome].AsString())
+	}
+
+	// Store span
+	if assert.Len(t, byName[spanStore], 1) {
+		store := spanAttributes(byName[spanStore][0])
+		assert.Equal(t, "store", store[attrOutcome].AsString())
+		assert.Positive(t, store[attrBodySize].AsInt64())
+	}
+
+	// One invalidation span for the resource and one per related resource
+	if assert.Len(t, byName[spanInvalidate], 3) {
+		product := spanAttributes(byName[spanInvalidate][0])
+		assert.Equal(t, "product", product[attrResource].AsString())
+		assert.Equal(t, "product", product[attrGroup].AsString())
+		assert.Equal(t, int64(2), product[attrGroupFanout].AsInt64())
+		assert.Equal(t, int64(1), product[attrKeysDeleted].AsInt64())
+		assert.Equal(t, "invalidate", product[attrOutcome].AsString())
+
+		category := spanAttributes(byName[spanInvalidate][1])
+		assert.Equal(t, "category", category[attrResource].AsString())
+		assert.Equal(t, int64(0), category[attrKeysDeleted].AsInt64())
+	}
+
+	// Cleanup
+	err = cache.DelWildCard(context.Background(), "/v1/*")
+	assert.NoError(t, err)
+}
+
+// TestTracing_InvalidateKeyHashed tests that exact keys only reach invalidation spans hashed
+func TestTracing_InvalidateKeyHashed(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	inv := NewInvalidator(cache, CacheConfig{TracerProvider: provider})
+	_, err = inv.InvalidateKey(context.Background(), "/v1/user?email=jane@example.com")
+	assert.NoError(t, err)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		attrs := spanAttributes(spans[0])
+		assert.Equal(t, hashKey("/v1/user?email=jane@example.com"), attrs[attrKeyHash].AsString())
+		assert.NotContains(t, attrs, attrPattern)
+		assert.Equal(t, "user", attrs[attrResource].AsString())
+	}
+}
@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// negativeKeySuffix is appended to a key to store its negative-cached loader error
+// Being a suffix, resource invalidation patterns such as "/v1/product*" clear it too
+const negativeKeySuffix = "#error"
+
+// TypedCacheConfig holds the configuration of a TypedCache
+type TypedCacheConfig struct {
+	// NegativeTTL caches loader errors for this long so a failing backend isn't hammered
+	// Zero disables negative caching
+	NegativeTTL time.Duration
+
+	// CacheError decides which loader errors are negative-cached (default all of them)
+	// Return false for transient errors such as timeouts
+	CacheError func(err error) bool
+}
+
+// TypedCache is a type-safe cache-aside wrapper around a Cache
+type TypedCache[T any] struct {
+	cache  Cache
+	config TypedCacheConfig
+	loads  singleflight.Group
+}
+
+// NewTypedCache creates a TypedCache for values of type T stored in cache
+func NewTypedCache[T any](cache Cache, config TypedCacheConfig) *TypedCache[T] {
+	return &TypedCache[T]{
+		cache:  cache,
+		config: config,
+	}
+}
+
+// Get returns the cached value for key
+// A miss is reported with found == false and a nil error
+func (tc *TypedCache[T]) Get(ctx context.Context, key string) (T, bool, error) {
+	var value T
+
+	err := tc.cache.Get(ctx, key, &value)
+	if errors.Is(err, ErrCacheMiss) {
+		return value, false, nil
+	}
+	if err != nil {
+		var zero T
+		return zero, false, err
+	}
+
+	return value, true, nil
+}
+
+// Set stores value for key with the given TTL
+func (tc *TypedCache[T]) Set(ctx context.Context, key string, value T, ttl time.Duration) error {
+	return tc.cache.Set(ctx, key, value, ttl)
+}
+
+// Del deletes keys together with their negative-cached errors
+func (tc *TypedCache[T]) Del(ctx context.Context, keys ...string) error {
+	all := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		all = append(all, key, key+negativeKeySuffix)
+	}
+	return tc.cache.Del(ctx, all...)
+}
+
+// GetOrLoad returns the cached value for key, calling loader and caching its result on a miss
+// Concurrent calls for the same key share a single loader call. The loader runs with a
+// context that is not cancelled when the first caller goes away, while every caller still
+// returns as soon as its own ctx is done. Cache failures never fail the call: the loader
+// result is returned even when Redis is unavailable.
+func (tc *TypedCache[T]) GetOrLoad(ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) (T, error)) (T, error) {
+	// Decode errors and backend failures fall through to the loader like a miss
+	if value, found, _ := tc.Get(ctx, key); found {
+		return value, nil
+	}
+
+	if tc.config.NegativeTTL > 0 {
+		var message string
+		if err := tc.cache.Get(ctx, key+negativeKeySuffix, &message); err == nil {
+			var zero T
+			return zero, fmt.Errorf("%w: %s", ErrCachedLoadFailure, message)
+		}
+	}
+
+	ch := tc.loads.DoChan(key, func() (interface{}, error) {
+		loadCtx := context.WithoutCancel(ctx)
+
+		loaded, err := loader(loadCtx)
+		if err != nil {
+			if tc.config.NegativeTTL > 0 && (tc.config.CacheError == nil || tc.config.CacheError(err)) {
+				_ = tc.cache.Set(loadCtx, key+negativeKeySuffix, err.Error(), tc.config.NegativeTTL)
+			}
+			return loaded, err
+		}
+
+		_ = tc.cache.Set(loadCtx, key, loaded, ttl)
+		return loaded, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+
+	case result := <-ch:
+		if result.Err != nil {
+			var zero T
+			return zero, result.Err
+		}
+		// A nil result of an interface T isn't a T, it's the zero value
+		loaded, _ := result.Val.(T)
+		return loaded, nil
+	}
+}
@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTypedCache_Get tests typed Get and Set
+func TestTypedCache_Get(t *testing.T) {
+	ctx := context.Background()
+
+	inner, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	users := NewTypedCache[TestObject](inner, TypedCacheConfig{})
+
+	_, found, err := users.Get(ctx, "test_typed_missing")
+	assert.NoError(t, err, "miss should not be an error")
+	assert.False(t, found)
+
+	expected := TestObject{ID: "1", Name: "Alice", Age: 25}
+	assert.NoError(t, users.Set(ctx, "test_typed_user", expected, 10*time.Second))
+
+	actual, found, err := users.Get(ctx, "test_typed_user")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, expected, actual)
+
+	// A value that doesn't decode into T is reported as an error
+	assert.NoError(t, inner.Set(ctx, "test_typed_user", "not an object", 10*time.Second))
+	_, found, err = users.Get(ctx, "test_typed_user")
+	assert.ErrorIs(t, err, ErrDecode)
+	assert.False(t, found)
+
+	assert.NoError(t, users.Del(ctx, "test_typed_user"))
+}
+
+// TestTypedCache_GetOrLoad tests that concurrent loads are deduplicated and the result is cached
+func TestTypedCache_GetOrLoad(t *testing.T) {
+	ctx := context.Background()
+
+	inner, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	users := NewTypedCache[TestObject](inner, TypedCacheConfig{})
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (TestObject, error) {
+		calls.Add(1)
+		<-release
+		return TestObject{ID: "2", Name: "Bob", Age: 30}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]TestObject, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = users.GetOrLoad(ctx, "test_typed_load", 10*time.Second, loader)
+		}(i)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load(), "concurrent loads should share one loader call")
+	for _, result := range results {
+		assert.Equal(t, "Bob", result.Name)
+	}
+
+	// Later calls are served from the cache
+	user, err := users.GetOrLoad(ctx, "test_typed_load", 10*time.Second, loader)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bob", user.Name)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// A caller whose context ends stops waiting
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = users.GetOrLoad(cancelled, "test_typed_slow", 10*time.Second, func(ctx context.Context) (TestObject, error) {
+		time.Sleep(50 * time.Millisecond)
+		return TestObject{}, nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.NoError(t, users.Del(ctx, "test_typed_load", "test_typed_slow"))
+}
+
+// TestTypedCache_NegativeCaching tests that loader errors are cached for NegativeTTL
+func TestTypedCache_NegativeCaching(t *testing.T) {
+	ctx := context.Background()
+
+	inner, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	errNotFound := errors.New("user not found")
+	errTimeout := errors.New("database timeout")
+
+	users := NewTypedCache[TestObject](inner, TypedCacheConfig{
+		NegativeTTL: 10 * time.Second,
+		CacheError: func(err error) bool {
+			return !errors.Is(err, errTimeout)
+		},
+	})
+
+	calls := 0
+	failing := func(err error) func(ctx context.Context) (TestObject, error) {
+		return func(ctx context.Context) (TestObject, error) {
+			calls++
+			return TestObject{}, err
+		}
+	}
+
+	// Transient errors are not cached
+	_, err = users.GetOrLoad(ctx, "test_typed_negative", 10*time.Second, failing(errTimeout))
+	assert.ErrorIs(t, err, errTimeout)
+	_, err = users.GetOrLoad(ctx, "test_typed_negative", 10*time.Second, failing(errTimeout))
+	assert.ErrorIs(t, err, errTimeout)
+	assert.Equal(t, 2, calls)
+
+	// Other errors are cached and the loader isn't called again
+	_, err = users.GetOrLoad(ctx, "test_typed_negative", 10*time.Second, failing(errNotFound))
+	assert.ErrorIs(t, err, errNotFound)
+	_, err = users.GetOrLoad(ctx, "test_typed_negative", 10*time.Second, failing(errNotFound))
+	assert.ErrorIs(t, err, ErrCachedLoadFailure)
+	assert.Contains(t, err.Error(), "user not found")
+	assert.Equal(t, 3, calls)
+
+	// Del clears the negative entry
+	assert.NoError(t, users.Del(ctx, "test_typed_negative"))
+	_, err = users.GetOrLoad(ctx, "test_typed_negative", 10*time.Second, failing(errNotFound))
+	assert.ErrorIs(t, err, errNotFound)
+	assert.Equal(t, 4, calls)
+
+	assert.NoError(t, users.Del(ctx, "test_typed_negative"))
+}
+
+// TestTypedCache_NilInterface tests that a loader returning a nil interface value doesn't panic
+func TestTypedCache_NilInterface(t *testing.T) {
+	ctx := context.Background()
+
+	inner, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	values := NewTypedCache[any](inner, TypedCacheConfig{})
+	_ = values.Del(ctx, "test_typed_nil")
+
+	value, err := values.GetOrLoad(ctx, "test_typed_nil", 10*time.Second, func(ctx context.Context) (any, error) {
+		return nil, nil
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+
+	assert.NoError(t, values.Del(ctx, "test_typed_nil"))
+}
@@ -0,0 +1,315 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// versionRegistryPrefix prefixes the sorted set of a namespace's versions, scored by last heartbeat
+// It never starts with a namespace prefix, so cleaning up a version leaves the registry alone
+const versionRegistryPrefix = "namespaces:"
+
+// versionCleanupLockSuffix is appended to the registry key for the cleanup lock
+const versionCleanupLockSuffix = ":cleanup"
+
+const (
+	// defaultVersionRetention is how long a version without a heartbeat is kept
+	defaultVersionRetention = time.Hour
+
+	// maxVersionHeartbeat is the longest interval between heartbeats of the active version
+	maxVersionHeartbeat = time.Minute
+
+	// versionHeartbeatTimeout bounds a single heartbeat
+	versionHeartbeatTimeout = 5 * time.Second
+
+	// versionCleanupLockTTL is how long the cleanup lock survives an instance that died holding it
+	// The holder refreshes it after every batch
+	versionCleanupLockTTL = time.Minute
+
+	// versionCleanupBatchSize is the SCAN COUNT hint and the UNLINK batch size of a cleanup
+	versionCleanupBatchSize = 500
+)
+
+// refreshLock extends the cleanup lock if this instance still holds it
+var refreshLock = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLock deletes the cleanup lock if this instance still holds it
+var releaseLock = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// VersionRegistry is implemented by caches that record the active versions of a namespace
+// so entries of old releases can be removed before they expire
+type VersionRegistry interface {
+	// RegisterVersion records a heartbeat of version in namespace
+	RegisterVersion(ctx context.Context, namespace, version string) error
+
+	// CleanupStaleVersions deletes the entries of every version of namespace other than current
+	// without a heartbeat for retention, and returns how many keys were deleted
+	CleanupStaleVersions(ctx context.Context, namespace, current string, retention time.Duration) (int64, error)
+}
+
+// RegisterVersion records a heartbeat of version in the namespace's sorted set
+func (r *redisCache) RegisterVersion(ctx context.Context, namespace, version string) error {
+	if err := r.ready(); err != nil {
+		return err
+	}
+
+	err := r.client.ZAdd(ctx, versionRegistryPrefix+namespace, redis.Z{
+		Score:  float64(time.Now().Unix()),
+		Member: version,
+	}).Err()
+	return r.backendError(err)
+}
+
+// CleanupStaleVersions deletes stale versions incrementally with SCAN and UNLINK
+// Only one instance cleans a namespace at a time; the others return immediately
+func (r *redisCache) CleanupStaleVersions(ctx context.Context, namespace, current string, retention time.Duration) (int64, error) {
+	if err := r.ready(); err != nil {
+		return 0, err
+	}
+
+	registry := versionRegistryPrefix + namespace
+	lock := registry + versionCleanupLockSuffix
+
+	token, err := lockToken()
+	if err != nil {
+		return 0, err
+	}
+
+	acquired, err := r.client.SetNX(ctx, lock, token, versionCleanupLockTTL).Result()
+	if err != nil {
+		return 0, r.backendError(err)
+	}
+	if !acquired {
+		return 0, nil
+	}
+	defer releaseLock.Run(context.WithoutCancel(ctx), r.client, []string{lock}, token)
+
+	cutoff := time.Now().Add(-retention).Unix()
+	stale, err := r.client.ZRangeByScore(ctx, registry, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: "(" + strconv.FormatInt(cutoff, 10),
+	}).Result()
+	if err != nil {
+		return 0, r.backendError(err)
+	}
+
+	var deleted int64
+	for _, version := range stale {
+		if version == current {
+			continue
+		}
+
+		// Entries and the tag sets and prefix index pointing at them
+		prefix := escapeGlob(keyNamespace(namespace, version))
+		for _, pattern := range []string{prefix + "*", tagKeyPrefix + prefix + "*", prefixIndexKeyPrefix + prefix} {
+			n, err := r.unlinkMatching(ctx, pattern, func() error {
+				return r.refreshCleanupLock(ctx, lock, token)
+			})
+			deleted += n
+			if errors.Is(err, errCleanupLockLost) {
+				return deleted, err
+			}
+			if err != nil {
+				return deleted, r.backendError(err)
+			}
+		}
+
+		// Only forget the version once all of its keys are gone, so an interrupted cleanup resumes
+		if err := r.client.ZRem(ctx, registry, version).Err(); err != nil {
+			return deleted, r.backendError(err)
+		}
+	}
+
+	return deleted, nil
+}
+
+// errCleanupLockLost stops a cleanup whose lock expired or was taken over by another instance
+var errCleanupLockLost = errors.New("cache: namespace cleanup lock lost")
+
+// refreshCleanupLock extends the cleanup lock, or reports errCleanupLockLost when it's no longer held
+func (r *redisCache) refreshCleanupLock(ctx context.Context, lock, token string) error {
+	refreshed, err := refreshLock.Run(ctx, r.client, []string{lock}, token, versionCleanupLockTTL.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if refreshed != 1 {
+		return errCleanupLockLost
+	}
+	return nil
+}
+
+// unlinkMatching unlinks every key matching pattern on every shard, one SCAN batch at a time
+// progress is called after every SCAN batch, even one without matches, and stops the cleanup when it fails
+func (r *redisCache) unlinkMatching(ctx context.Context, pattern string, progress func() error) (int64, error) {
+	var deleted atomic.Int64
+
+	scan := func(ctx context.Context, client redis.Cmdable) error {
+		var cursor uint64
+		for {
+			keys, next, err := client.Scan(ctx, cursor, pattern, versionCleanupBatchSize).Result()
+			if err != nil {
+				return err
+			}
+
+			if len(keys) > 0 {
+				// Single-key commands keep cluster shards from rejecting cross-slot batches
+				cmds, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+					for _, key := range keys {
+						pipe.Unlink(ctx, key)
+					}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+				for _, cmd := range cmds {
+					deleted.Add(cmd.(*redis.IntCmd).Val())
+				}
+			}
+
+			// Sparse matches can take many batches, so the lock is refreshed on every one
+			if err := progress(); err != nil {
+				return err
+			}
+
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	}
+
+	err := r.forEachShard(ctx, scan)
+	return deleted.Load(), err
+}
+
+// lockToken returns a random token identifying the holder of a lock
+func lockToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// async runs fn on the inner cache, so its Shutdown drains it
+func (s *secureCache) async(fn func()) bool {
+	return runAsync(s.inner, fn)
+}
+
+// RegisterVersion records the version on the inner cache
+func (s *secureCache) RegisterVersion(ctx context.Context, namespace, version string) error {
+	return registerVersion(ctx, s.inner, namespace, version)
+}
+
+// CleanupStaleVersions deletes stale versions on the inner cache
+func (s *secureCache) CleanupStaleVersions(ctx context.Context, namespace, current string, retention time.Duration) (int64, error) {
+	return cleanupStaleVersions(ctx, s.inner, namespace, current, retention)
+}
+
+// versionHeartbeat records the active version of a namespace at most once per interval
+type versionHeartbeat struct {
+	cache     Cache
+	namespace string
+	version   string
+	interval  time.Duration
+	last      atomic.Int64
+}
+
+// newVersionHeartbeat returns nil when there is no version to record or nowhere to record it
+func newVersionHeartbeat(cache Cache, config CacheConfig, retention time.Duration) *versionHeartbeat {
+	if _, ok := cache.(VersionRegistry); !ok || config.Version == "" {
+		return nil
+	}
+
+	return &versionHeartbeat{
+		cache:     cache,
+		namespace: config.Namespace,
+		version:   config.Version,
+		interval:  min(maxVersionHeartbeat, retention/4),
+	}
+}
+
+// beat records the version in the background when the last heartbeat is older than the interval
+func (h *versionHeartbeat) beat() {
+	if h == nil {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	last := h.last.Load()
+	if now-last < h.interval.Nanoseconds() || !h.last.CompareAndSwap(last, now) {
+		return
+	}
+
+	// Shutdown waits for the write; after it has started the heartbeat is dropped
+	runAsync(h.cache, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), versionHeartbeatTimeout)
+		defer cancel()
+
+		// A failed heartbeat is retried after the next interval
+		_ = registerVersion(ctx, h.cache, h.namespace, h.version)
+	})
+}
+
+// CleanupStaleNamespaces deletes the entries of old releases of the namespace, i.e. versions
+// other than CacheConfig.Version without a heartbeat for VersionRetention
+// It is safe to run from several instances at once, e.g. on a ticker or at startup; while one
+// instance cleans up, the others return immediately. A cleanup that loses its lock stops with
+// an error and the next call resumes it. The cache must implement VersionRegistry.
+func (inv *Invalidator) CleanupStaleNamespaces(ctx context.Context) (int64, error) {
+	return cleanupStaleVersions(ctx, inv.cache, inv.rawNamespace, inv.version, inv.versionRetention)
+}
+
+// asyncRunner is implemented by caches that track background work until Shutdown
+type asyncRunner interface {
+	async(fn func()) bool
+}
+
+// runAsync runs fn in the background, tracked by the cache when it implements asyncRunner
+// It reports false when the cache is shutting down and fn was dropped
+func runAsync(cache Cache, fn func()) bool {
+	if runner, ok := cache.(asyncRunner); ok {
+		return runner.async(fn)
+	}
+
+	go fn()
+	return true
+}
+
+// errVersionsUnsupported is returned for version operations on caches that don't implement VersionRegistry
+var errVersionsUnsupported = fmt.Errorf("cache: namespace versions: %w", errors.ErrUnsupported)
+
+// registerVersion records a version on any Cache
+func registerVersion(ctx context.Context, cache Cache, namespace, version string) error {
+	if registry, ok := cache.(VersionRegistry); ok {
+		return registry.RegisterVersion(ctx, namespace, version)
+	}
+	return errVersionsUnsupported
+}
+
+// cleanupStaleVersions deletes stale versions on any Cache
+func cleanupStaleVersions(ctx context.Context, cache Cache, namespace, current string, retention time.Duration) (int64, error) {
+	if registry, ok := cache.(VersionRegistry); ok {
+		return registry.CleanupStaleVersions(ctx, namespace, current, retention)
+	}
+	return 0, errVersionsUnsupported
+}
@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestVersions_Heartbeat tests that serving requests records the active version
+func TestVersions_Heartbeat(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+	client.Del(ctx, "namespaces:heartbeat")
+
+	router := setupTestRouter(cache, CacheConfig{TTL: 10 * time.Second, Namespace: "heartbeat", Version: "v7"})
+	router.GET("/v1/product", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/product", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The heartbeat runs in the background
+	assert.Eventually(t, func() bool {
+		score, err := client.ZScore(ctx, "namespaces:heartbeat", "v7").Result()
+		return err == nil && score > 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+// TestVersions_Cleanup tests that entries of stale versions are deleted and the active and recent versions kept
+func TestVersions_Cleanup(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+
+	registry := "namespaces:cleanup"
+	client.Del(ctx, registry, registry+":cleanup")
+
+	old := float64(time.Now().Add(-2 * time.Hour).Unix())
+	recent := float64(time.Now().Add(-10 * time.Minute).Unix())
+	client.ZAdd(ctx, registry,
+		redis.Z{Score: old, Member: "v1"},
+		redis.Z{Score: old, Member: "v[2]"},
+		redis.Z{Score: old, Member: "v4"},
+		redis.Z{Score: recent, Member: "v3"},
+	)
+
+	keys := []string{
+		"cleanup@v1:/v1/product", "cleanup@v1:/v1/product/1", "tag:cleanup@v1:catalog",
+		"cleanup@v[2]:/v1/product",
+		"cleanup@v3:/v1/product",
+		"cleanup@v4:/v1/product",
+		"cleanup@v2:/v1/product", "cleanup:/v1/product", "cleanup@v10:/v1/product",
+	}
+	for _, key := range keys {
+		client.Set(ctx, key, "cached", time.Minute)
+	}
+
+	// v4 is stale but active, e.g. an instance that hasn't served a request for a while
+	inv := NewInvalidator(cache, CacheConfig{Namespace: "cleanup", Version: "v4"})
+	deleted, err := inv.CleanupStaleNamespaces(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), deleted)
+
+	for _, key := range keys[:4] {
+		assert.Equal(t, int64(0), client.Exists(ctx, key).Val(), key)
+	}
+	for _, key := range keys[4:] {
+		assert.Equal(t, int64(1), client.Exists(ctx, key).Val(), key)
+	}
+
+	versions, err := client.ZRange(ctx, registry, 0, -1).Result()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"v3", "v4"}, versions)
+	assert.Equal(t, int64(0), client.Exists(ctx, registry+":cleanup").Val())
+
+	// A shorter retention makes v3 stale too
+	inv = NewInvalidator(cache, CacheConfig{Namespace: "cleanup", Version: "v4", VersionRetention: time.Minute})
+	deleted, err = inv.CleanupStaleNamespaces(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	assert.Equal(t, int64(0), client.Exists(ctx, "cleanup@v3:/v1/product").Val())
+
+	client.Del(ctx, keys...)
+	client.Del(ctx, registry)
+}
+
+// TestVersions_CleanupLocked tests that only one instance cleans up a namespace at a time
+func TestVersions_CleanupLocked(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+
+	registry := "namespaces:locked"
+	client.ZAdd(ctx, registry, redis.Z{Score: 1, Member: "v1"})
+	client.Set(ctx, "locked@v1:/v1/product", "cached", time.Minute)
+	client.Set(ctx, registry+":cleanup", "other-instance", time.Minute)
+
+	inv := NewInvalidator(cache, CacheConfig{Namespace: "locked", Version: "v2"})
+	deleted, err := inv.CleanupStaleNamespaces(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), deleted)
+	assert.Equal(t, int64(1), client.Exists(ctx, "locked@v1:/v1/product").Val())
+
+	// The lock of another instance is never released
+	assert.Equal(t, "other-instance", client.Get(ctx, registry+":cleanup").Val())
+
+	client.Del(ctx, registry, registry+":cleanup", "locked@v1:/v1/product")
+}
+
+// TestVersions_HeartbeatDrained tests that Shutdown waits for a pending heartbeat and later ones are dropped
+func TestVersions_HeartbeatDrained(t *testing.T) {
+	ctx := context.Background()
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+	client.Del(ctx, "namespaces:drain")
+
+	cache, err := NewRedisCacheFromClient(client, RedisConfig{})
+	assert.NoError(t, err)
+
+	inv := NewInvalidator(cache, CacheConfig{Namespace: "drain", Version: "v1"})
+	inv.heartbeat.beat()
+	assert.NoError(t, cache.(Shutdowner).Shutdown(ctx))
+	assert.NoError(t, client.ZScore(ctx, "namespaces:drain", "v1").Err(), "Shutdown should wait for the heartbeat")
+
+	client.Del(ctx, "namespaces:drain")
+	inv.heartbeat.last.Store(0)
+	inv.heartbeat.beat()
+	time.Sleep(50 * time.Millisecond)
+	assert.ErrorIs(t, client.ZScore(ctx, "namespaces:drain", "v1").Err(), redis.Nil, "heartbeats after Shutdown should be dropped")
+}
+
+// TestVersions_CleanupLockRefresh tests that the cleanup lock is refreshed after every SCAN batch and a lost lock stops the cleanup
+func TestVersions_CleanupLockRefresh(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := NewRedisCache(RedisConfig{Host: "localhost", Port: 6379})
+	assert.NoError(t, err)
+	r := cache.(*redisCache)
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+
+	lock := "namespaces:refresh:cleanup"
+	client.Set(ctx, lock, "mine", time.Second)
+
+	assert.NoError(t, r.refreshCleanupLock(ctx, lock, "mine"))
+	assert.Greater(t, client.PTTL(ctx, lock).Val(), time.Second, "the lock should be extended")
+
+	// Batches without matches still report progress
+	progress := 0
+	_, err = r.unlinkMatching(ctx, "refresh-nothing:*", func() error {
+		progress++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Positive(t, progress)
+
+	// Another instance took the lock over
+	client.Set(ctx, lock, "theirs", time.Minute)
+	assert.ErrorIs(t, r.refreshCleanupLock(ctx, lock, "mine"), errCleanupLockLost)
+
+	client.Set(ctx, "refresh@v1:/v1/product", "cached", time.Minute)
+	_, err = r.unlinkMatching(ctx, "refresh@v1:*", func() error {
+		return r.refreshCleanupLock(ctx, lock, "mine")
+	})
+	assert.ErrorIs(t, err, errCleanupLockLost)
+
+	client.Del(ctx, lock, "refresh@v1:/v1/product")
+}